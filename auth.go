@@ -0,0 +1,511 @@
+package supabasego
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// AuthClient provides access to the Supabase Auth (GoTrue) API.
+type AuthClient struct {
+	client *Client
+}
+
+// Auth returns an AuthClient for the Supabase Auth API.
+func (c *Client) Auth() *AuthClient {
+	return &AuthClient{client: c}
+}
+
+// GetURL returns the absolute URL of an Auth API path, e.g. GetURL("authorize?provider=github")
+// returns https://<project>.supabase.co/auth/v1/authorize?provider=github. Extra or missing
+// slashes and a leading /auth/v1 in path are handled, so it is safe for building custom
+// redirect flows. Every Auth request the SDK makes uses it too.
+func (a *AuthClient) GetURL(path string) string {
+	base := strings.TrimRight(a.client.BaseURL, "/") + AUTH_URL
+	path = strings.TrimLeft(path, "/")
+	if rest, ok := strings.CutPrefix(path, strings.TrimPrefix(AUTH_URL, "/")); ok && (rest == "" || strings.ContainsAny(rest[:1], "/?#")) {
+		path = strings.TrimLeft(rest, "/")
+	}
+	if path == "" || path[0] == '?' || path[0] == '#' {
+		return base + path
+	}
+	return base + "/" + path
+}
+
+// User is a Supabase Auth user.
+type User struct {
+	ID               string                 `json:"id"`
+	Aud              string                 `json:"aud,omitempty"`
+	Role             string                 `json:"role,omitempty"`
+	Email            string                 `json:"email,omitempty"`
+	Phone            string                 `json:"phone,omitempty"`
+	EmailConfirmedAt *time.Time             `json:"email_confirmed_at,omitempty"`
+	PhoneConfirmedAt *time.Time             `json:"phone_confirmed_at,omitempty"`
+	LastSignInAt     *time.Time             `json:"last_sign_in_at,omitempty"`
+	AppMetadata      map[string]interface{} `json:"app_metadata,omitempty"`
+	UserMetadata     map[string]interface{} `json:"user_metadata,omitempty"`
+	Identities       []Identity             `json:"identities,omitempty"`
+	Factors          []Factor               `json:"factors,omitempty"`
+	IsAnonymous      bool                   `json:"is_anonymous,omitempty"`
+	CreatedAt        time.Time              `json:"created_at"`
+	UpdatedAt        time.Time              `json:"updated_at"`
+}
+
+// Identity is an auth provider identity (email, phone, or OAuth) linked to a user.
+type Identity struct {
+	ID           string                 `json:"id"`          // Provider-specific user ID
+	IdentityID   string                 `json:"identity_id"` // Used by UnlinkIdentity
+	UserID       string                 `json:"user_id"`
+	Provider     string                 `json:"provider"`
+	IdentityData map[string]interface{} `json:"identity_data,omitempty"`
+	CreatedAt    time.Time              `json:"created_at"`
+	LastSignInAt *time.Time             `json:"last_sign_in_at,omitempty"`
+	UpdatedAt    time.Time              `json:"updated_at"`
+}
+
+// Factor is an MFA factor enrolled by a user.
+type Factor struct {
+	ID           string    `json:"id"`
+	FactorType   string    `json:"factor_type"` // e.g. "totp"
+	FriendlyName string    `json:"friendly_name,omitempty"`
+	Status       string    `json:"status"` // "verified" or "unverified"
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// AuthResponse is the session returned by sign-in and verification endpoints.
+type AuthResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	ExpiresAt    int64  `json:"expires_at,omitempty"`
+	RefreshToken string `json:"refresh_token"`
+	User         *User  `json:"user,omitempty"`
+}
+
+// GetUser fetches the user that owns the given JWT.
+func (a *AuthClient) GetUser(jwtToken string) (*User, error) {
+	req, err := a.client.newRequestURL("GET", a.GetURL("user"), nil, jwtToken)
+	if err != nil {
+		return nil, err
+	}
+	var user User
+	if err := a.client.doJSON(req, "get user", &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserFromToken reads the user from the claims of an access token without a network
+// request: ID (from sub), Aud, Role, Email, Phone, AppMetadata, UserMetadata and IsAnonymous.
+// Other fields, such as Identities and the timestamps, are only available from GetUser.
+//
+// The token's signature is NOT verified, so a forged token is accepted as long as it is not
+// expired. Only use it where the token has already been verified (e.g. by your API gateway or
+// a JWT library with the project's JWT secret) or where a wrong user ID does no harm; use
+// GetUser otherwise. Expired tokens fail with an error wrapping ErrUnauthorized.
+func (a *AuthClient) GetUserFromToken(jwtToken string) (*User, error) {
+	var claims struct {
+		jwtClaims
+		Audience     json.RawMessage        `json:"aud"` // A string or an array of strings
+		Email        string                 `json:"email"`
+		Phone        string                 `json:"phone"`
+		Role         string                 `json:"role"`
+		AppMetadata  map[string]interface{} `json:"app_metadata"`
+		UserMetadata map[string]interface{} `json:"user_metadata"`
+		IsAnonymous  bool                   `json:"is_anonymous"`
+	}
+	if err := decodeJWTClaims(jwtToken, &claims); err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("supabase: JWT has no sub claim")
+	}
+	if claims.ExpiresAt > 0 && time.Now().Unix() >= claims.ExpiresAt {
+		return nil, fmt.Errorf("%w: JWT expired at %s", ErrUnauthorized, time.Unix(claims.ExpiresAt, 0).UTC().Format(time.RFC3339))
+	}
+	user := &User{
+		ID:           claims.Subject,
+		Email:        claims.Email,
+		Phone:        claims.Phone,
+		Role:         claims.Role,
+		AppMetadata:  claims.AppMetadata,
+		UserMetadata: claims.UserMetadata,
+		IsAnonymous:  claims.IsAnonymous,
+	}
+	var aud []string
+	if json.Unmarshal(claims.Audience, &user.Aud) != nil && json.Unmarshal(claims.Audience, &aud) == nil && len(aud) > 0 {
+		user.Aud = aud[0]
+	}
+	return user, nil
+}
+
+// GetUserIdentities lists the identities (OAuth providers, email, phone) linked to the user that owns the JWT.
+func (a *AuthClient) GetUserIdentities(jwtToken string) ([]Identity, error) {
+	user, err := a.GetUser(jwtToken)
+	if err != nil {
+		return nil, err
+	}
+	return user.Identities, nil
+}
+
+// UnlinkIdentity removes a linked identity from the user that owns the JWT.
+// identityID is the Identity.IdentityID value returned by GetUserIdentities.
+func (a *AuthClient) UnlinkIdentity(identityID, jwtToken string) error {
+	req, err := a.client.newRequestURL("DELETE", a.GetURL("user/identities/"+url.PathEscape(identityID)), nil, jwtToken)
+	if err != nil {
+		return err
+	}
+	return a.client.doJSON(req, "unlink identity", nil)
+}
+
+// LinkIdentity starts linking an OAuth provider (e.g. "google") to the user that owns the JWT
+// and returns the provider URL to send their browser to. Once the flow completes the identity
+// is listed by GetUserIdentities. Manual linking must be enabled for the project.
+func (a *AuthClient) LinkIdentity(provider string, opts OAuthOptions, jwtToken string) (string, error) {
+	params := url.Values{}
+	for k, v := range opts.QueryParams {
+		params.Set(k, v)
+	}
+	params.Set("provider", provider)
+	params.Set("skip_http_redirect", "true")
+	if opts.RedirectTo != "" {
+		params.Set("redirect_to", opts.RedirectTo)
+	}
+	if len(opts.Scopes) > 0 {
+		params.Set("scopes", strings.Join(opts.Scopes, " "))
+	}
+	req, err := a.client.newRequestURL("GET", a.GetURL("user/identities/authorize?"+params.Encode()), nil, jwtToken)
+	if err != nil {
+		return "", err
+	}
+	var res struct {
+		URL string `json:"url"`
+	}
+	if err := a.client.doJSON(req, "link identity", &res); err != nil {
+		return "", err
+	}
+	return res.URL, nil
+}
+
+// AnonymousSignInOptions configures SignInAnonymously.
+type AnonymousSignInOptions struct {
+	Data map[string]interface{} // Optional user metadata
+}
+
+// SignInAnonymously creates a transient anonymous user and returns its session, whose access
+// token works with the table API like any other (the JWT has the is_anonymous claim for RLS).
+// Anonymous sign-ins must be enabled for the project. Anonymous users have no email or phone,
+// so they cannot be looked up by email until they are converted to a permanent user by adding
+// an email or linking an identity.
+func (a *AuthClient) SignInAnonymously(opts AnonymousSignInOptions) (*AuthResponse, error) {
+	body := map[string]interface{}{"data": opts.Data}
+	if opts.Data == nil {
+		body["data"] = map[string]interface{}{}
+	}
+	req, err := a.client.newRequestURL("POST", a.GetURL("signup"), body, "")
+	if err != nil {
+		return nil, err
+	}
+	var res AuthResponse
+	if err := a.client.doJSON(req, "sign in anonymously", &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// emailOTPTypes are the OTP types accepted by VerifyEmailOTP.
+var emailOTPTypes = map[string]bool{
+	"signup":    true,
+	"magiclink": true,
+	"recovery":  true,
+	"email":     true,
+}
+
+type verifyOTPRequest struct {
+	Type  string `json:"type"`
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+	Token string `json:"token"`
+}
+
+// VerifyEmailOTP verifies a one-time code sent to an email address and returns the resulting session.
+// otpType must be one of "signup", "magiclink", "recovery" or "email".
+func (a *AuthClient) VerifyEmailOTP(email, token, otpType string) (*AuthResponse, error) {
+	if !emailOTPTypes[otpType] {
+		return nil, fmt.Errorf("supabase: invalid email OTP type %q", otpType)
+	}
+	return a.verifyOTP(verifyOTPRequest{Type: otpType, Email: email, Token: token})
+}
+
+// VerifyPhoneOTP verifies a one-time code sent by SMS and returns the resulting session.
+func (a *AuthClient) VerifyPhoneOTP(phone, token string) (*AuthResponse, error) {
+	return a.verifyOTP(verifyOTPRequest{Type: "sms", Phone: phone, Token: token})
+}
+
+// VerifyOTPAuto verifies a one-time code sent to either an email address or a phone number,
+// detecting which from contact: an address containing "@" is verified as an email OTP, and
+// one that starts with "+" or is all digits as an SMS OTP. This suits a single "enter your
+// code" screen. Anything else is rejected without a request.
+func (a *AuthClient) VerifyOTPAuto(contact, token string) (*AuthResponse, error) {
+	switch {
+	case strings.Contains(contact, "@"):
+		return a.VerifyEmailOTP(contact, token, "email")
+	case isPhoneNumber(contact):
+		return a.VerifyPhoneOTP(contact, token)
+	}
+	return nil, fmt.Errorf("supabase: %q is neither an email address nor a phone number", contact)
+}
+
+// isPhoneNumber reports whether s starts with "+" or is all digits.
+func isPhoneNumber(s string) bool {
+	if strings.HasPrefix(s, "+") {
+		return len(s) > 1
+	}
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *AuthClient) verifyOTP(body verifyOTPRequest) (*AuthResponse, error) {
+	req, err := a.client.newRequestURL("POST", a.GetURL("verify"), body, "")
+	if err != nil {
+		return nil, err
+	}
+	var res AuthResponse
+	if err := a.client.doJSON(req, "verify otp", &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+type resendRequest struct {
+	Type    string        `json:"type"`
+	Email   string        `json:"email"`
+	Options resendOptions `json:"options"`
+}
+
+type resendOptions struct {
+	EmailRedirectTo string `json:"emailRedirectTo,omitempty"`
+}
+
+// ResendConfirmationEmail re-sends the signup confirmation email to a user who has not confirmed yet.
+// redirectTo is optional. Returns ErrAlreadyConfirmed if the email is already confirmed.
+func (a *AuthClient) ResendConfirmationEmail(email, redirectTo string) error {
+	body := resendRequest{
+		Type:    "signup",
+		Email:   email,
+		Options: resendOptions{EmailRedirectTo: redirectTo},
+	}
+	req, err := a.client.newRequestURL("POST", a.GetURL("resend"), body, "")
+	if err != nil {
+		return err
+	}
+	err = a.client.doJSON(req, "resend confirmation email", nil)
+	if errors.Is(err, ErrConflict) {
+		return ErrAlreadyConfirmed
+	}
+	return err
+}
+
+// TOTPEnrollResponse is returned by EnrollMFA. Show QRCode (or TOTPSecret) to the user so they
+// can add the factor to their authenticator app, then confirm it with VerifyMFA.
+type TOTPEnrollResponse struct {
+	ID           string
+	FriendlyName string
+	TOTPSecret   string
+	QRCode       string // SVG image
+	URI          string // otpauth:// URI
+}
+
+// EnrollMFA starts enrolling a new TOTP factor for the user that owns the JWT.
+func (a *AuthClient) EnrollMFA(friendlyName string, jwtToken string) (*TOTPEnrollResponse, error) {
+	body := map[string]string{"factor_type": "totp", "friendly_name": friendlyName}
+	req, err := a.client.newRequestURL("POST", a.GetURL("factors"), body, jwtToken)
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		ID           string `json:"id"`
+		FriendlyName string `json:"friendly_name"`
+		TOTP         struct {
+			QRCode string `json:"qr_code"`
+			Secret string `json:"secret"`
+			URI    string `json:"uri"`
+		} `json:"totp"`
+	}
+	if err := a.client.doJSON(req, "enroll mfa", &res); err != nil {
+		return nil, err
+	}
+	return &TOTPEnrollResponse{
+		ID:           res.ID,
+		FriendlyName: res.FriendlyName,
+		TOTPSecret:   res.TOTP.Secret,
+		QRCode:       res.TOTP.QRCode,
+		URI:          res.TOTP.URI,
+	}, nil
+}
+
+// VerifyMFA verifies a TOTP code for a factor, which completes enrollment of a new factor or
+// upgrades the session to aal2. It creates the required challenge itself; use ChallengeMFA and
+// VerifyMFAChallenge to run the two steps separately.
+func (a *AuthClient) VerifyMFA(factorID, code string, jwtToken string) (*AuthResponse, error) {
+	challenge, err := a.ChallengeMFA(factorID, jwtToken)
+	if err != nil {
+		return nil, err
+	}
+	return a.VerifyMFAChallenge(factorID, challenge.ID, code, jwtToken)
+}
+
+// MFAChallenge is a pending second-factor challenge.
+type MFAChallenge struct {
+	ID        string `json:"id"`
+	Type      string `json:"type,omitempty"` // e.g. "totp"
+	ExpiresAt int64  `json:"expires_at"`     // Unix seconds
+}
+
+// ChallengeMFA starts the second step of an MFA sign-in by creating a challenge for a factor.
+// Answer it with VerifyMFAChallenge before it expires.
+func (a *AuthClient) ChallengeMFA(factorID, jwtToken string) (*MFAChallenge, error) {
+	req, err := a.client.newRequestURL("POST", a.GetURL("factors/"+url.PathEscape(factorID)+"/challenge"), struct{}{}, jwtToken)
+	if err != nil {
+		return nil, err
+	}
+	var challenge MFAChallenge
+	if err := a.client.doJSON(req, "challenge mfa", &challenge); err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// VerifyMFAChallenge answers a challenge with the user's TOTP code and returns the upgraded
+// (aal2) session.
+func (a *AuthClient) VerifyMFAChallenge(factorID, challengeID, code string, jwtToken string) (*AuthResponse, error) {
+	body := map[string]string{"challenge_id": challengeID, "code": code}
+	req, err := a.client.newRequestURL("POST", a.GetURL("factors/"+url.PathEscape(factorID)+"/verify"), body, jwtToken)
+	if err != nil {
+		return nil, err
+	}
+	var res AuthResponse
+	if err := a.client.doJSON(req, "verify mfa", &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// UnenrollMFA removes a factor from the user that owns the JWT.
+func (a *AuthClient) UnenrollMFA(factorID, jwtToken string) error {
+	req, err := a.client.newRequestURL("DELETE", a.GetURL("factors/"+url.PathEscape(factorID)), nil, jwtToken)
+	if err != nil {
+		return err
+	}
+	return a.client.doJSON(req, "unenroll mfa", nil)
+}
+
+// ExchangeCodeForSession completes the PKCE flow: it trades the auth code from the redirect
+// URL for a session, proving possession of the verifier generated with GeneratePKCEPair.
+func (a *AuthClient) ExchangeCodeForSession(code, codeVerifier string) (*AuthResponse, error) {
+	body := map[string]string{"auth_code": code, "code_verifier": codeVerifier}
+	req, err := a.client.newRequestURL("POST", a.GetURL("token?grant_type=pkce"), body, "")
+	if err != nil {
+		return nil, err
+	}
+	var res AuthResponse
+	if err := a.client.doJSON(req, "exchange code", &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// GeneratePKCEPair returns a random PKCE code verifier and its S256 challenge (the base64url
+// SHA-256 of the verifier). Send the challenge when starting the flow and keep the verifier
+// for ExchangeCodeForSession.
+func GeneratePKCEPair() (verifier, challenge string, err error) {
+	verifier, err = randomToken()
+	if err != nil {
+		return "", "", fmt.Errorf("supabase: failed to generate PKCE verifier: %w", err)
+	}
+	return verifier, pkceChallenge(verifier), nil
+}
+
+// randomToken returns 32 random bytes encoded as base64url.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// pkceChallenge returns the S256 code challenge for verifier (RFC 7636).
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// OAuthOptions configures SignInWithOAuth.
+type OAuthOptions struct {
+	RedirectTo  string            // Where the provider sends the user back; must be allowed in the project's redirect URLs
+	Scopes      []string          // Extra provider scopes to request
+	QueryParams map[string]string // Extra parameters passed on to the provider, e.g. access_type=offline
+}
+
+// OAuthInitResult is returned by SignInWithOAuth.
+type OAuthInitResult struct {
+	URL          string // Send the user's browser here
+	CodeVerifier string // Keep server-side (e.g. in the session) for ExchangeCodeForSession
+	State        string // Random value added to RedirectTo as the state parameter, to check in the callback
+}
+
+// SignInWithOAuth starts the PKCE OAuth flow for provider (e.g. "github", "google"). It
+// generates the code verifier and challenge, and returns the authorize URL to redirect the
+// user to. When the user comes back to RedirectTo with a code, compare the state query
+// parameter with State and pass the code and CodeVerifier to ExchangeCodeForSession. No
+// request is made. State is only sent back when RedirectTo is set.
+func (a *AuthClient) SignInWithOAuth(provider string, opts OAuthOptions) (*OAuthInitResult, error) {
+	verifier, challenge, err := GeneratePKCEPair()
+	if err != nil {
+		return nil, err
+	}
+	state, err := randomToken()
+	if err != nil {
+		return nil, fmt.Errorf("supabase: failed to generate OAuth state: %w", err)
+	}
+	params := url.Values{}
+	for k, v := range opts.QueryParams {
+		params.Set(k, v)
+	}
+	params.Set("provider", provider)
+	params.Set("code_challenge", challenge)
+	params.Set("code_challenge_method", "s256")
+	if len(opts.Scopes) > 0 {
+		params.Set("scopes", strings.Join(opts.Scopes, " "))
+	}
+	if opts.RedirectTo != "" {
+		redirect, err := url.Parse(opts.RedirectTo)
+		if err != nil {
+			return nil, fmt.Errorf("supabase: invalid RedirectTo: %w", err)
+		}
+		q := redirect.Query()
+		q.Set("state", state)
+		redirect.RawQuery = q.Encode()
+		params.Set("redirect_to", redirect.String())
+	}
+	return &OAuthInitResult{
+		URL:          a.GetURL("authorize?" + params.Encode()),
+		CodeVerifier: verifier,
+		State:        state,
+	}, nil
+}
@@ -0,0 +1,69 @@
+package supabasego
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// BatchOp is one operation of a Batch.
+type BatchOp struct {
+	Method  string      // e.g. "POST"
+	Path    string      // Relative to the client's BaseURL, e.g. REST_URL + "/tenants?id=eq.1"
+	Headers http.Header // Optional extra headers, e.g. Prefer
+	Body    interface{} // Marshalled as JSON; nil sends no body
+}
+
+// BatchResult is the response to one BatchOp.
+type BatchResult struct {
+	StatusCode int
+	Body       []byte
+	Headers    http.Header
+}
+
+// Batch queues operations to run together, e.g. for bulk data loading. PostgREST has no
+// multi-operation endpoint, so Execute sends the operations one after another over the client's
+// pooled keep-alive connections rather than in a single HTTP request.
+type Batch struct {
+	client *Client
+	ops    []BatchOp
+}
+
+// Batch returns an empty Batch.
+func (c *Client) Batch() *Batch {
+	return &Batch{client: c}
+}
+
+// Add queues an operation.
+func (b *Batch) Add(op BatchOp) *Batch {
+	b.ops = append(b.ops, op)
+	return b
+}
+
+// Execute runs the queued operations in order and returns one result per operation. Error
+// statuses are reported in the results rather than as an error, so one failed operation does
+// not hide the others. A non-nil error means an operation could not be sent; the results of the
+// operations before it are returned with it and later operations are not run.
+func (b *Batch) Execute(jwtToken string) ([]BatchResult, error) {
+	results := make([]BatchResult, 0, len(b.ops))
+	for i, op := range b.ops {
+		req, err := b.client.newRequest(op.Method, op.Path, op.Body, jwtToken)
+		if err != nil {
+			return results, fmt.Errorf("batch operation %d: %w", i, err)
+		}
+		for name, values := range op.Headers {
+			req.Header[name] = values
+		}
+		resp, err := b.client.Do(req)
+		if err != nil {
+			return results, fmt.Errorf("batch operation %d request failed: %w", i, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return results, fmt.Errorf("batch operation %d: failed to read response: %w", i, err)
+		}
+		results = append(results, BatchResult{StatusCode: resp.StatusCode, Body: body, Headers: resp.Header})
+	}
+	return results, nil
+}
@@ -0,0 +1,78 @@
+package supabasego
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitBreakerConfig enables a circuit breaker around HTTP calls. After Threshold consecutive
+// failures (network errors or 5xx responses) requests fail fast with ErrCircuitOpen for
+// ResetTimeout; then a single probe request is let through to test recovery.
+type CircuitBreakerConfig struct {
+	Threshold    int           // Zero disables the breaker
+	ResetTimeout time.Duration // Defaults to 30s
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+type circuitBreaker struct {
+	threshold    int
+	resetTimeout time.Duration
+	now          func() time.Time
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+}
+
+func newCircuitBreaker(cfg CircuitBreakerConfig) *circuitBreaker {
+	if cfg.Threshold <= 0 {
+		return nil
+	}
+	reset := cfg.ResetTimeout
+	if reset <= 0 {
+		reset = 30 * time.Second
+	}
+	return &circuitBreaker{threshold: cfg.Threshold, resetTimeout: reset, now: time.Now}
+}
+
+// allow reports whether a request may be sent. Once the reset timeout has elapsed it lets a
+// single probe through (half-open) and rejects others until the probe reports back.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case circuitOpen:
+		if b.now().Sub(b.openedAt) < b.resetTimeout {
+			return false
+		}
+		b.state = circuitHalfOpen
+		return true
+	case circuitHalfOpen:
+		return false
+	}
+	return true
+}
+
+// record reports the outcome of a request that allow let through.
+func (b *circuitBreaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if success {
+		b.state = circuitClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.threshold {
+		b.state = circuitOpen
+		b.openedAt = b.now()
+	}
+}
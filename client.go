@@ -1,6 +1,12 @@
 package supabasego
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"time"
 )
@@ -10,6 +16,16 @@ type Client struct {
 	BaseURL    string // e.g. https://<project>.supabase.co
 	APIKey     string // Supabase anon or service key
 	HTTPClient *http.Client
+
+	// ManagementAPIKey is a personal access token for the Management API, needed only by admin
+	// clients such as FunctionsAdminClient. ManagementURL defaults to MANAGEMENT_API_URL.
+	ManagementAPIKey string
+	ManagementURL    string
+
+	breaker     *circuitBreaker
+	gets        *flightGroup // Set when Config.DeduplicateGets is enabled
+	maxBodySize int64
+	log         *slog.Logger // Config.Logger; nil uses slog's default logger
 }
 
 // Config holds configuration for the Supabase client.
@@ -17,29 +33,204 @@ type Config struct {
 	BaseURL string
 	APIKey  string
 	Timeout time.Duration // Optional: HTTP timeout
+	// HTTPClient optionally replaces the default HTTP client. It is copied, not modified.
+	HTTPClient *http.Client
+	// CircuitBreaker optionally fails requests fast while Supabase is unavailable.
+	CircuitBreaker CircuitBreakerConfig
+	// DeduplicateGets coalesces concurrent identical GET requests (same URL and headers) into a
+	// single HTTP call whose response is shared by all callers.
+	DeduplicateGets bool
+	// Logger, if set, logs every HTTP request through a LoggingInterceptor. See WithLogger.
+	Logger *slog.Logger
+	// ManagementAPIKey is a personal access token for the Supabase Management API. It is
+	// separate from APIKey and only needed for project administration (e.g. Functions().Admin()).
+	ManagementAPIKey string
+	// ManagementURL optionally overrides MANAGEMENT_API_URL.
+	ManagementURL string
+	// MaxBodySize, if positive, caps the size in bytes of the JSON body sent by Insert, InsertMaps,
+	// Update and Upsert; larger bodies fail with ErrBodyTooLarge before any request is made.
+	MaxBodySize int64
 }
 
 // NewClient creates a new Supabase API client.
 func NewClient(cfg Config) *Client {
 	client := &http.Client{}
+	if cfg.HTTPClient != nil {
+		hc := *cfg.HTTPClient
+		client = &hc
+	}
 	if cfg.Timeout > 0 {
 		client.Timeout = cfg.Timeout
 	}
-	return &Client{
-		BaseURL:    cfg.BaseURL,
-		APIKey:     cfg.APIKey,
-		HTTPClient: client,
+	if cfg.Logger != nil {
+		client.Transport = &LoggingInterceptor{Logger: cfg.Logger, Next: client.Transport}
+	}
+	c := &Client{
+		BaseURL:     cfg.BaseURL,
+		APIKey:      cfg.APIKey,
+		HTTPClient:  client,
+		breaker:     newCircuitBreaker(cfg.CircuitBreaker),
+		maxBodySize: cfg.MaxBodySize,
+		log:         cfg.Logger,
+
+		ManagementAPIKey: cfg.ManagementAPIKey,
+		ManagementURL:    cfg.ManagementURL,
+	}
+	if cfg.DeduplicateGets {
+		c.gets = newFlightGroup()
+	}
+	return c
+}
+
+// WithServiceRole returns a copy of the client that authenticates with the service role key,
+// for admin operations such as the Auth admin API. It shares the original client's HTTP client
+// and settings; the original is not modified.
+//
+// The service role bypasses Row Level Security and can read and change all data. Only use it
+// server-side and never expose the key to browsers or mobile apps. A warning is logged to
+// Config.Logger (or slog's default logger) if serviceKey is the same as the client's APIKey,
+// which usually means the anon key was passed by mistake.
+func (c *Client) WithServiceRole(serviceKey string) *Client {
+	if serviceKey == c.APIKey {
+		c.logger().Warn("supabase: WithServiceRole called with the client's own API key; pass the service role key")
 	}
+	sc := *c
+	sc.APIKey = serviceKey
+	return &sc
+}
+
+// logger returns the logger set with Config.Logger, or slog's default logger.
+func (c *Client) logger() *slog.Logger {
+	if c.log != nil {
+		return c.log
+	}
+	return slog.Default()
+}
+
+// Ping checks that Supabase is reachable and accepts the API key by making a cheap request to the
+// REST API. Call it at startup to fail fast on a misconfigured client. The error says whether
+// the server could not be reached, the key was rejected, or the project is over its quota, and
+// wraps the underlying error (e.g. ErrUnauthorized) for errors.Is.
+func (c *Client) Ping(ctx context.Context) error {
+	req, err := c.newRequest("GET", REST_URL+"/?limit=0", nil, "")
+	if err != nil {
+		return err
+	}
+	resp, err := c.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("supabase: ping failed: cannot reach %s: %w", c.BaseURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 400 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	err = responseError("ping", resp)
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("supabase: ping failed: invalid API key: %w", err)
+	case http.StatusPaymentRequired, http.StatusTooManyRequests:
+		return fmt.Errorf("supabase: ping failed: quota exceeded: %w", err)
+	}
+	return err
 }
 
 // newRequest creates a new HTTP request with Supabase headers.
+// path is relative to BaseURL (e.g. REST_URL + "/todos"); body, if non-nil, is sent as JSON.
 func (c *Client) newRequest(method, path string, body interface{}, jwtToken string) (*http.Request, error) {
-	// Implementation will handle marshalling body, setting headers, etc.
-	// To be filled in as CRUD and auth are implemented.
-	return nil, nil
+	return c.newRequestURL(method, c.BaseURL+path, body, jwtToken)
+}
+
+// newRequestURL is newRequest for an absolute URL, for services that may live outside BaseURL.
+func (c *Client) newRequestURL(method, url string, body interface{}, jwtToken string) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, url, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("apikey", c.APIKey)
+	if jwtToken != "" {
+		req.Header.Set("Authorization", "Bearer "+jwtToken)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	return req, nil
+}
+
+// newRawRequest is like newRequest but sends body as-is with the given content type.
+func (c *Client) newRawRequest(method, path string, body io.Reader, contentType, jwtToken string) (*http.Request, error) {
+	req, err := http.NewRequest(method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("apikey", c.APIKey)
+	if jwtToken != "" {
+		req.Header.Set("Authorization", "Bearer "+jwtToken)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
+
+// doJSON sends req and decodes the JSON response into dest (skipped when dest is nil).
+// op names the operation in error messages, e.g. "get user".
+func (c *Client) doJSON(req *http.Request, op string, dest interface{}) error {
+	return c.doJSONWith(c.HTTPClient, req, op, dest)
+}
+
+// doJSONWith is doJSON using hc instead of c.HTTPClient.
+func (c *Client) doJSONWith(hc *http.Client, req *http.Request, op string, dest interface{}) error {
+	resp, err := c.doWith(hc, req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", op, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return responseError(op, resp)
+	}
+	if dest == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(dest); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", op, err)
+	}
+	return nil
 }
 
 // Do sends an HTTP request and returns the response.
 func (c *Client) Do(req *http.Request) (*http.Response, error) {
-	return c.HTTPClient.Do(req)
+	return c.doWith(c.HTTPClient, req)
+}
+
+// doWith sends req using hc instead of c.HTTPClient.
+func (c *Client) doWith(hc *http.Client, req *http.Request) (*http.Response, error) {
+	if c.gets != nil && req.Method == http.MethodGet {
+		return c.gets.do(req.Context(), flightKey(req), func() (*http.Response, error) {
+			return c.send(hc, req)
+		})
+	}
+	return c.send(hc, req)
+}
+
+// send performs a single HTTP round trip, guarded by the circuit breaker if configured.
+func (c *Client) send(hc *http.Client, req *http.Request) (*http.Response, error) {
+	if c.breaker == nil {
+		return hc.Do(req)
+	}
+	if !c.breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+	resp, err := hc.Do(req)
+	c.breaker.record(err == nil && resp.StatusCode < 500)
+	return resp, err
 }
@@ -0,0 +1,55 @@
+package supabasego
+
+import (
+	"fmt"
+)
+
+// IncrementFunctionSQL creates the supabase_sdk_increment function that IncrementCol and
+// DecrementCol call. Run it once, e.g. in a migration or the SQL editor. It runs with the
+// caller's privileges, so Row Level Security still applies.
+const IncrementFunctionSQL = `create or replace function supabase_sdk_increment(table_name text, col text, filter jsonb, amount int)
+returns void
+language plpgsql
+as $$
+declare
+  conditions text := '';
+  k text;
+  v jsonb;
+begin
+  for k, v in select * from jsonb_each(filter) loop
+    conditions := conditions || format(' and %I = %L', k, v #>> '{}');
+  end loop;
+  execute format('update %I set %I = %I + $1 where true%s', table_name, col, col, conditions) using amount;
+end;
+$$;`
+
+// IncrementCol atomically adds by to col in the rows matching the table's filters, without the
+// read-modify-write race of fetching and updating the row. PostgREST cannot express
+// col = col + n, so this calls the supabase_sdk_increment function, which must be created first
+// with IncrementFunctionSQL. Only Eq filters with non-nil values are supported, and at least
+// one is required so that a missing filter cannot update every row in the table.
+func (t *Table) IncrementCol(col string, by int, jwtToken string) error {
+	if len(t.filters) == 0 {
+		return fmt.Errorf("supabase: IncrementCol requires at least one Eq filter")
+	}
+	filter := map[string]interface{}{}
+	for _, f := range t.filters {
+		sf, ok := f.(simpleFilter)
+		if !ok || sf.op != "eq" || isNilValue(sf.value) {
+			return fmt.Errorf("supabase: IncrementCol only supports Eq filters, got %v", f)
+		}
+		filter[sf.field] = sf.value
+	}
+	params := map[string]interface{}{
+		"table_name": t.tableName,
+		"col":        col,
+		"filter":     filter,
+		"amount":     by,
+	}
+	return t.client.RPC("supabase_sdk_increment", params, jwtToken).Execute(nil)
+}
+
+// DecrementCol atomically subtracts by from col. See IncrementCol.
+func (t *Table) DecrementCol(col string, by int, jwtToken string) error {
+	return t.IncrementCol(col, -by, jwtToken)
+}
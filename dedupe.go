@@ -0,0 +1,108 @@
+package supabasego
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// flightGroup coalesces concurrent identical requests into one HTTP call, in the manner of
+// golang.org/x/sync/singleflight, fanning a buffered copy of the response out to every caller.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done   chan struct{} // Closed when the call has finished
+	status int
+	proto  string
+	header http.Header
+	body   []byte
+	err    error
+
+	// canceled is set when the call failed because the context of the caller that made it
+	// ended, an error that does not apply to the other callers.
+	canceled bool
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{calls: map[string]*flightCall{}}
+}
+
+// do runs fn once for all concurrent callers with the same key. ctx is the caller's request
+// context: a caller waiting on another's call stops when its own ctx ends, and if the call
+// fails because the context of the caller that made it ended, the waiting callers try again
+// rather than receiving that caller's cancellation error.
+func (g *flightGroup) do(ctx context.Context, key string, fn func() (*http.Response, error)) (*http.Response, error) {
+	for {
+		g.mu.Lock()
+		call, ok := g.calls[key]
+		if !ok {
+			break
+		}
+		g.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if !call.canceled {
+			return call.response()
+		}
+	}
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	resp, err := fn()
+	if err == nil {
+		call.status, call.proto, call.header = resp.StatusCode, resp.Proto, resp.Header
+		call.body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	call.err = err
+	call.canceled = err != nil && ctx.Err() != nil
+
+	// Remove the call before releasing the waiters so that any retrying start a new one.
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(call.done)
+	return call.response()
+}
+
+// response returns a fresh copy of the shared response for one caller.
+func (c *flightCall) response() (*http.Response, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &http.Response{
+		StatusCode:    c.status,
+		Status:        http.StatusText(c.status),
+		Proto:         c.proto,
+		Header:        c.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(c.body)),
+		ContentLength: int64(len(c.body)),
+	}, nil
+}
+
+// flightKey identifies a request by its full URL and headers, so requests made with different
+// credentials are never coalesced.
+func flightKey(req *http.Request) string {
+	var b strings.Builder
+	b.WriteString(req.URL.String())
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteString("\n" + name + ": " + strings.Join(req.Header[name], ","))
+	}
+	return b.String()
+}
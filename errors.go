@@ -0,0 +1,148 @@
+package supabasego
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors returned by the SDK. Use errors.Is to check for them.
+var (
+	// ErrBadRequest is returned when the server responds with 400 Bad Request.
+	ErrBadRequest = errors.New("supabase: bad request")
+	// ErrUnauthorized is returned when the server responds with 401 Unauthorized.
+	ErrUnauthorized = errors.New("supabase: unauthorized")
+	// ErrForbidden is returned when the server responds with 403 Forbidden.
+	ErrForbidden = errors.New("supabase: forbidden")
+	// ErrNotFound is returned when the server responds with 404 Not Found.
+	ErrNotFound = errors.New("supabase: not found")
+	// ErrConflict is returned when the server responds with 409 Conflict.
+	ErrConflict = errors.New("supabase: conflict")
+	// ErrTooManyRequests is returned when the server responds with 429 Too Many Requests.
+	ErrTooManyRequests = errors.New("supabase: too many requests")
+	// ErrServer is returned when the server responds with a 5xx status.
+	ErrServer = errors.New("supabase: server error")
+	// ErrNoRows is returned when a single row was requested but none matched.
+	ErrNoRows = errors.New("supabase: no rows in result")
+	// ErrTooManyRows is returned when a single row was requested but several matched.
+	ErrTooManyRows = errors.New("supabase: more than one row in result")
+	// ErrMultipleRows is an alias of ErrTooManyRows.
+	ErrMultipleRows = ErrTooManyRows
+	// ErrBodyTooLarge is returned without sending the request when a request body exceeds
+	// Config.MaxBodySize.
+	ErrBodyTooLarge = errors.New("supabase: request body too large")
+	// ErrCircuitOpen is returned without sending the request while the circuit breaker is open.
+	ErrCircuitOpen = errors.New("supabase: circuit breaker open")
+	// ErrNotConnected is returned by Realtime operations that need an open connection.
+	ErrNotConnected = errors.New("supabase: realtime not connected")
+	// ErrChannelNotJoined is returned when sending on a Realtime channel that is not joined.
+	ErrChannelNotJoined = errors.New("supabase: realtime channel not joined")
+	// ErrAlreadyConfirmed is returned by ResendConfirmationEmail when the user has already confirmed their email.
+	ErrAlreadyConfirmed = errors.New("supabase: email already confirmed")
+)
+
+// PostgrestError is the JSON error body returned by PostgREST, e.g.
+// {"code":"42P01","message":"relation \"public.missing\" does not exist","details":null,"hint":null}.
+// Code is a PostgreSQL SQLSTATE (such as "23505" for unique violations, or the ERRCODE of an
+// exception raised in a function) or a PostgREST "PGRST..." code.
+type PostgrestError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details string `json:"details"`
+	Hint    string `json:"hint"`
+}
+
+func (e *PostgrestError) Error() string {
+	msg := "supabase: " + e.Message
+	if e.Code != "" {
+		msg = fmt.Sprintf("supabase: %s (%s)", e.Message, e.Code)
+	}
+	if e.Details != "" {
+		msg += ": " + e.Details
+	}
+	if e.Hint != "" {
+		msg += " (hint: " + e.Hint + ")"
+	}
+	return msg
+}
+
+// APIError is returned when a Supabase API responds with a status code of 400 or above.
+// It unwraps to one of the sentinel errors (ErrNotFound, ErrUnauthorized, ...) where one applies.
+// When the body is a PostgREST error its fields (Code, Message, Details, Hint) are set, and
+// errors.As can also extract it as a *PostgrestError.
+type APIError struct {
+	Op         string // Operation that failed, e.g. "select"
+	StatusCode int
+	Body       string // Raw response body
+	PostgrestError
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("supabase: %s failed: %s", e.Op, e.Body)
+}
+
+// Unwrap returns the sentinel error matching the status code, or nil.
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return ErrBadRequest
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusNotAcceptable:
+		// PostgREST answers single-object requests that don't match exactly one row with PGRST116.
+		if strings.Contains(e.Body, "PGRST116") {
+			if strings.Contains(e.Body, " 0 rows") {
+				return ErrNoRows
+			}
+			return ErrTooManyRows
+		}
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusTooManyRequests:
+		return ErrTooManyRequests
+	}
+	if e.StatusCode >= 500 {
+		return ErrServer
+	}
+	return nil
+}
+
+// As lets errors.As extract the PostgREST error body as a *PostgrestError.
+func (e *APIError) As(target interface{}) bool {
+	pg, ok := target.(**PostgrestError)
+	if !ok || (e.Code == "" && e.Message == "") {
+		return false
+	}
+	*pg = &e.PostgrestError
+	return true
+}
+
+// responseError builds the error for a failed (status >= 400) response.
+func responseError(op string, resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	return statusError(op, resp.StatusCode, body)
+}
+
+// statusError builds the error for a failed request from its status code and response body.
+func statusError(op string, statusCode int, body []byte) error {
+	e := &APIError{Op: op, StatusCode: statusCode, Body: string(body)}
+	// Bodies that are not PostgREST errors (or not JSON) leave PostgrestError empty.
+	var pg struct {
+		PostgrestError
+		Detail string `json:"detail"` // Some services use the singular form
+	}
+	if json.Unmarshal(body, &pg) == nil {
+		e.PostgrestError = pg.PostgrestError
+		if e.Details == "" {
+			e.Details = pg.Detail
+		}
+	}
+	return e
+}
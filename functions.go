@@ -0,0 +1,269 @@
+package supabasego
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FunctionsClient invokes Supabase Edge Functions.
+type FunctionsClient struct {
+	client  *Client
+	baseURL string // Set by WithBaseURL
+
+	mu     sync.Mutex
+	before []func(name string, req *http.Request) error
+	after  []func(name string, resp *http.Response, err error)
+}
+
+// Functions returns a FunctionsClient for the Supabase Edge Functions API.
+func (c *Client) Functions() *FunctionsClient {
+	return &FunctionsClient{client: c}
+}
+
+// WithBaseURL returns a FunctionsClient that invokes functions at url (e.g.
+// http://localhost:54321/functions/v1 for the Supabase CLI) instead of the project's
+// /functions/v1, without affecting the REST, Auth or other clients. Hooks added to f so far
+// are copied to the new client.
+func (f *FunctionsClient) WithBaseURL(url string) *FunctionsClient {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &FunctionsClient{
+		client:  f.client,
+		baseURL: strings.TrimRight(url, "/"),
+		before:  append(([]func(string, *http.Request) error)(nil), f.before...),
+		after:   append(([]func(string, *http.Response, error))(nil), f.after...),
+	}
+}
+
+// BeforeInvoke adds a hook that is called with the function name and request before every
+// invocation, e.g. to add headers or log calls. Hooks run in the order they were added; if one
+// returns an error the function is not called and Invoke returns that error.
+func (f *FunctionsClient) BeforeInvoke(hook func(name string, req *http.Request) error) {
+	f.mu.Lock()
+	f.before = append(f.before, hook)
+	f.mu.Unlock()
+}
+
+// AfterInvoke adds a hook that is called after every invocation with the function name and
+// either the response or the error, e.g. to record metrics. Hooks run in the order they were
+// added. The response body has not been read yet; a hook that reads it must replace it so the
+// caller still gets the body.
+func (f *FunctionsClient) AfterInvoke(hook func(name string, resp *http.Response, err error)) {
+	f.mu.Lock()
+	f.after = append(f.after, hook)
+	f.mu.Unlock()
+}
+
+// do sends an invocation of funcName, running the BeforeInvoke and AfterInvoke hooks.
+func (f *FunctionsClient) do(funcName string, req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	before, after := f.before, f.after
+	f.mu.Unlock()
+	var resp *http.Response
+	var err error
+	for _, hook := range before {
+		if err = hook(funcName, req); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		resp, err = f.client.Do(req)
+	}
+	for _, hook := range after {
+		hook(funcName, resp, err)
+	}
+	return resp, err
+}
+
+// functionsURL returns the base URL functions are invoked at: the WithBaseURL override, the
+// Supabase CLI default (LOCAL_FUNCTIONS_URL) when the client points at localhost without a port,
+// or the project's /functions/v1. A localhost URL with a port, such as the CLI's :54321 or a
+// self-hosted stack's :8000, already serves functions at /functions/v1 and is kept as is.
+func (f *FunctionsClient) functionsURL() string {
+	if f.baseURL != "" {
+		return f.baseURL
+	}
+	if u, err := url.Parse(f.client.BaseURL); err == nil && u.Scheme == "http" && u.Hostname() == "localhost" && u.Port() == "" {
+		return LOCAL_FUNCTIONS_URL
+	}
+	return f.client.BaseURL + FUNCTIONS_URL
+}
+
+// newRequest creates a POST request invoking funcName.
+func (f *FunctionsClient) newRequest(funcName string, body interface{}, jwtToken string) (*http.Request, error) {
+	return f.client.newRequestURL("POST", f.functionsURL()+"/"+funcName, body, f.token(jwtToken))
+}
+
+// token returns jwtToken, or the client's API key when jwtToken is empty.
+func (f *FunctionsClient) token(jwtToken string) string {
+	if jwtToken != "" {
+		return jwtToken
+	}
+	return f.client.APIKey
+}
+
+// FunctionResponse is the full HTTP response of an Edge Function invocation.
+type FunctionResponse struct {
+	StatusCode int
+	Headers    http.Header
+	Body       []byte
+}
+
+// Invoke calls the named Edge Function with body marshalled as JSON (nil sends no body)
+// and returns the raw response body. A status code of 400 or above is returned as an error.
+func (f *FunctionsClient) Invoke(funcName string, body interface{}, jwtToken string) ([]byte, error) {
+	res, err := f.InvokeWithResponse(funcName, body, jwtToken)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= 400 {
+		return nil, statusError("invoke "+funcName, res.StatusCode, res.Body)
+	}
+	return res.Body, nil
+}
+
+// InvokeWithResponse calls the named Edge Function and returns its status code, headers and body.
+// Unlike Invoke, error status codes are not treated as failures; inspect StatusCode instead.
+func (f *FunctionsClient) InvokeWithResponse(funcName string, body interface{}, jwtToken string) (*FunctionResponse, error) {
+	req, err := f.newRequest(funcName, body, jwtToken)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.do(funcName, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &FunctionResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Header,
+		Body:       b,
+	}, nil
+}
+
+// Invoke calls the named Edge Function with req marshalled as JSON and decodes the JSON
+// response into Resp, so both sides are type-checked:
+//
+//	type greetReq struct{ Name string `json:"name"` }
+//	type greetResp struct{ Message string `json:"message"` }
+//	res, err := supabasego.Invoke[greetReq, greetResp](client.Functions(), "greet", greetReq{Name: "Ada"}, jwt)
+func Invoke[Req any, Resp any](f *FunctionsClient, funcName string, req Req, jwtToken string) (Resp, error) {
+	var resp Resp
+	body, err := f.Invoke(funcName, req, jwtToken)
+	if err != nil {
+		return resp, err
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return resp, fmt.Errorf("failed to decode invoke %s response: %w", funcName, err)
+	}
+	return resp, nil
+}
+
+// InvokeStream calls the named Edge Function and returns the response body unread so the caller
+// can consume a streamed (chunked or Server-Sent Events) response. The caller must close it.
+// Use ReadSSEEvents to parse an event stream.
+func (f *FunctionsClient) InvokeStream(funcName string, body interface{}, jwtToken string) (io.ReadCloser, error) {
+	req, err := f.newRequest(funcName, body, jwtToken)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := f.do(funcName, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, responseError("invoke "+funcName, resp)
+	}
+	return resp.Body, nil
+}
+
+// SSEEvent is a single Server-Sent Event.
+type SSEEvent struct {
+	ID    string
+	Event string // Empty means the default "message" event
+	Data  string // Multiple data lines are joined with "\n"
+	Retry int    // Reconnection time in milliseconds, if sent
+
+	// Err is set, on an otherwise empty final event, if the stream could not be read to the end,
+	// e.g. because the connection dropped or a line exceeded 1 MB.
+	Err error
+}
+
+// ReadSSEEvents parses Server-Sent Events from r and sends them on the returned channel, which
+// is closed when r is exhausted or returns an error. A read error is sent as a final event with
+// Err set. As the SSE spec requires, blocks without data lines are not dispatched and an
+// unterminated event at the end of the stream is discarded. To stop early, close r and receive
+// until the channel is closed, or use ReadSSEEventsCtx.
+func ReadSSEEvents(r io.Reader) <-chan SSEEvent {
+	return ReadSSEEventsCtx(context.Background(), r)
+}
+
+// ReadSSEEventsCtx is like ReadSSEEvents but also stops, closing the channel, when ctx is
+// cancelled, without the caller having to receive the remaining events. A read already blocked
+// on r still only ends when r is closed.
+func ReadSSEEventsCtx(ctx context.Context, r io.Reader) <-chan SSEEvent {
+	events := make(chan SSEEvent)
+	go func() {
+		defer close(events)
+		send := func(ev SSEEvent) bool {
+			select {
+			case events <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		var ev SSEEvent
+		var data []string
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == "" {
+				if len(data) > 0 {
+					ev.Data = strings.Join(data, "\n")
+					if !send(ev) {
+						return
+					}
+				}
+				ev, data = SSEEvent{}, nil
+				continue
+			}
+			if strings.HasPrefix(line, ":") {
+				continue // comment
+			}
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				ev.Event = value
+			case "data":
+				data = append(data, value)
+			case "id":
+				ev.ID = value
+			case "retry":
+				if n, err := strconv.Atoi(value); err == nil {
+					ev.Retry = n
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil && ctx.Err() == nil {
+			send(SSEEvent{Err: fmt.Errorf("supabase: reading event stream failed: %w", err)})
+		}
+	}()
+	return events
+}
@@ -0,0 +1,110 @@
+package supabasego
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// FunctionsAdminClient discovers the Edge Functions deployed to the project through the
+// Supabase Management API. It needs Config.ManagementAPIKey.
+type FunctionsAdminClient struct {
+	client *Client
+}
+
+// Admin returns a FunctionsAdminClient. Only use it server-side.
+func (f *FunctionsClient) Admin() *FunctionsAdminClient {
+	return &FunctionsAdminClient{client: f.client}
+}
+
+// FunctionMetadata describes a deployed Edge Function.
+type FunctionMetadata struct {
+	ID        string
+	Slug      string
+	Name      string
+	Status    string // e.g. "ACTIVE"
+	Version   int
+	VerifyJWT bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// functionResponse is a function as returned by the Management API, with epoch-millisecond
+// timestamps.
+type functionResponse struct {
+	ID        string `json:"id"`
+	Slug      string `json:"slug"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Version   int    `json:"version"`
+	VerifyJWT bool   `json:"verify_jwt"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+func (r functionResponse) metadata() FunctionMetadata {
+	return FunctionMetadata{
+		ID:        r.ID,
+		Slug:      r.Slug,
+		Name:      r.Name,
+		Status:    r.Status,
+		Version:   r.Version,
+		VerifyJWT: r.VerifyJWT,
+		CreatedAt: time.UnixMilli(r.CreatedAt).UTC(),
+		UpdatedAt: time.UnixMilli(r.UpdatedAt).UTC(),
+	}
+}
+
+// List returns every Edge Function deployed to the project.
+func (f *FunctionsAdminClient) List() ([]FunctionMetadata, error) {
+	req, err := f.request("/functions")
+	if err != nil {
+		return nil, err
+	}
+	var res []functionResponse
+	if err := f.client.doJSON(req, "list functions", &res); err != nil {
+		return nil, err
+	}
+	functions := make([]FunctionMetadata, len(res))
+	for i, r := range res {
+		functions[i] = r.metadata()
+	}
+	return functions, nil
+}
+
+// Get returns the Edge Function with the given slug. Returns ErrNotFound if it does not exist.
+func (f *FunctionsAdminClient) Get(name string) (*FunctionMetadata, error) {
+	req, err := f.request("/functions/" + url.PathEscape(name))
+	if err != nil {
+		return nil, err
+	}
+	var res functionResponse
+	if err := f.client.doJSON(req, "get function", &res); err != nil {
+		return nil, err
+	}
+	meta := res.metadata()
+	return &meta, nil
+}
+
+// request builds a Management API GET request for a path under the project.
+func (f *FunctionsAdminClient) request(path string) (*http.Request, error) {
+	ref, err := projectRef(f.client.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	return f.client.newManagementRequest(http.MethodGet, "/projects/"+ref+path)
+}
+
+// projectRef returns the project reference from a project URL such as
+// https://abcdefghijklmnop.supabase.co.
+func projectRef(baseURL string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err == nil {
+		if ref, ok := strings.CutSuffix(u.Hostname(), ".supabase.co"); ok && ref != "" && !strings.Contains(ref, ".") {
+			return ref, nil
+		}
+	}
+	return "", fmt.Errorf("supabase: cannot determine the project ref from BaseURL %q", baseURL)
+}
@@ -0,0 +1,99 @@
+package supabasego
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// logBodyLimit caps how much of a request or response body is included in debug logs.
+const logBodyLimit = 1024
+
+// WithLogger sets the structured logger used to log every HTTP request made by the client.
+// Bodies (truncated to 1 KB) are logged when the logger has Debug level enabled.
+func WithLogger(logger *slog.Logger) Option {
+	return func(cfg *Config) {
+		cfg.Logger = logger
+	}
+}
+
+// LoggingInterceptor is an http.RoundTripper that logs each request with slog: method, url,
+// status_code, duration_ms, table (for REST calls) and error. NewClient installs it when
+// Config.Logger is set; it can also wrap a custom transport directly.
+type LoggingInterceptor struct {
+	Logger *slog.Logger
+	Next   http.RoundTripper // Defaults to http.DefaultTransport
+}
+
+// RoundTrip implements http.RoundTripper.
+func (l *LoggingInterceptor) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := l.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	ctx := req.Context()
+	debug := l.Logger.Enabled(ctx, slog.LevelDebug)
+
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.String()),
+	}
+	if table := tableFromPath(req.URL.Path); table != "" {
+		attrs = append(attrs, slog.String("table", table))
+	}
+	if debug && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			attrs = append(attrs, slog.String("request_body", readLogBody(body)))
+			body.Close()
+		}
+	}
+
+	start := time.Now()
+	resp, err := next.RoundTrip(req)
+	attrs = append(attrs, slog.Int64("duration_ms", time.Since(start).Milliseconds()))
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		l.Logger.LogAttrs(ctx, slog.LevelError, "supabase request failed", attrs...)
+		return nil, err
+	}
+
+	attrs = append(attrs, slog.Int("status_code", resp.StatusCode))
+	if debug {
+		peek, _ := io.ReadAll(io.LimitReader(resp.Body, logBodyLimit))
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(peek), resp.Body), resp.Body}
+		attrs = append(attrs, slog.String("response_body", string(peek)))
+	}
+	level := slog.LevelInfo
+	switch {
+	case resp.StatusCode >= 500:
+		level = slog.LevelError
+	case resp.StatusCode >= 400:
+		level = slog.LevelWarn
+	}
+	l.Logger.LogAttrs(ctx, level, "supabase request", attrs...)
+	return resp, nil
+}
+
+// tableFromPath returns the table name of a REST API path such as /rest/v1/tenants.
+func tableFromPath(path string) string {
+	i := strings.Index(path, REST_URL+"/")
+	if i < 0 {
+		return ""
+	}
+	table, _, _ := strings.Cut(path[i+len(REST_URL)+1:], "/")
+	return table
+}
+
+func readLogBody(r io.Reader) string {
+	b, _ := io.ReadAll(io.LimitReader(r, logBodyLimit+1))
+	if len(b) > logBodyLimit {
+		return string(b[:logBodyLimit]) + "...(truncated)"
+	}
+	return string(b)
+}
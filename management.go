@@ -0,0 +1,83 @@
+package supabasego
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ManagementClient calls the Supabase Management API (https://api.supabase.com/v1) for
+// account-level administration. Unlike Client it is not tied to one project and authenticates
+// with a personal access token rather than a project API key.
+type ManagementClient struct {
+	client *Client
+}
+
+// NewManagementClient creates a ManagementClient authenticated with a personal access token.
+func NewManagementClient(accessToken string) *ManagementClient {
+	return &ManagementClient{client: NewClient(Config{ManagementAPIKey: accessToken})}
+}
+
+// Project describes a Supabase project.
+type Project struct {
+	ID             string          `json:"id"`
+	Ref            string          `json:"ref"`
+	OrganizationID string          `json:"organization_id"`
+	Name           string          `json:"name"`
+	Region         string          `json:"region"`
+	Status         string          `json:"status"` // e.g. "ACTIVE_HEALTHY"
+	CreatedAt      time.Time       `json:"created_at"`
+	Database       ProjectDatabase `json:"database"`
+}
+
+// ProjectDatabase describes a project's Postgres database.
+type ProjectDatabase struct {
+	Host    string `json:"host"`
+	Version string `json:"version"`
+}
+
+// ListProjects returns every project the access token can see.
+func (m *ManagementClient) ListProjects() ([]Project, error) {
+	req, err := m.client.newManagementRequest(http.MethodGet, "/projects")
+	if err != nil {
+		return nil, err
+	}
+	var projects []Project
+	if err := m.client.doJSON(req, "list projects", &projects); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// GetProject returns the project with the given ref. Returns ErrNotFound if it does not exist.
+func (m *ManagementClient) GetProject(ref string) (*Project, error) {
+	req, err := m.client.newManagementRequest(http.MethodGet, "/projects/"+url.PathEscape(ref))
+	if err != nil {
+		return nil, err
+	}
+	var project Project
+	if err := m.client.doJSON(req, "get project", &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// newManagementRequest creates a Management API request authenticated with ManagementAPIKey.
+// path is relative to ManagementURL (or MANAGEMENT_API_URL), e.g. "/projects".
+func (c *Client) newManagementRequest(method, path string) (*http.Request, error) {
+	if c.ManagementAPIKey == "" {
+		return nil, fmt.Errorf("supabase: ManagementAPIKey is required for the Management API")
+	}
+	base := c.ManagementURL
+	if base == "" {
+		base = MANAGEMENT_API_URL
+	}
+	req, err := http.NewRequest(method, base+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.ManagementAPIKey)
+	req.Header.Set("Accept", "application/json")
+	return req, nil
+}
@@ -0,0 +1,59 @@
+package supabasego
+
+import (
+	"net/http"
+	"time"
+)
+
+// Option configures a client created with NewClientWithOptions.
+type Option func(*Config)
+
+// NewClientWithOptions creates a new Supabase API client from functional options.
+//
+//	client := supabasego.NewClientWithOptions(
+//		supabasego.WithBaseURL("https://<project>.supabase.co"),
+//		supabasego.WithAPIKey(key),
+//		supabasego.WithTimeout(10*time.Second),
+//	)
+func NewClientWithOptions(opts ...Option) *Client {
+	var cfg Config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return NewClient(cfg)
+}
+
+// WithBaseURL sets the project URL, e.g. https://<project>.supabase.co.
+func WithBaseURL(url string) Option {
+	return func(cfg *Config) {
+		cfg.BaseURL = url
+	}
+}
+
+// WithAPIKey sets the Supabase anon or service key.
+func WithAPIKey(key string) Option {
+	return func(cfg *Config) {
+		cfg.APIKey = key
+	}
+}
+
+// WithManagementAPIKey sets the personal access token used for the Supabase Management API.
+func WithManagementAPIKey(token string) Option {
+	return func(cfg *Config) {
+		cfg.ManagementAPIKey = token
+	}
+}
+
+// WithTimeout sets the HTTP timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.Timeout = d
+	}
+}
+
+// WithHTTPClient uses c as the base HTTP client.
+func WithHTTPClient(c *http.Client) Option {
+	return func(cfg *Config) {
+		cfg.HTTPClient = c
+	}
+}
@@ -0,0 +1,174 @@
+package supabasego
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// QueryBuilder holds the state of a read query (filters, ordering, pagination, columns)
+// separately from its execution, so queries can be built and inspected without HTTP.
+// Run it with Execute.
+type QueryBuilder struct {
+	client     *Client
+	tableName  string
+	filters    []Filter
+	orders     []order
+	limit      int
+	offset     int
+	selectCols []string
+}
+
+// From returns a QueryBuilder for the given table.
+func (c *Client) From(table string) *QueryBuilder {
+	return &QueryBuilder{client: c, tableName: table}
+}
+
+// Where adds filters built with Eq, Gt, In, And, Or, etc.
+func (qb *QueryBuilder) Where(filters ...Filter) *QueryBuilder {
+	qb.filters = append(qb.filters, filters...)
+	return qb
+}
+
+// OrderBy adds an order clause (direction should be "asc" or "desc").
+func (qb *QueryBuilder) OrderBy(field, direction string) *QueryBuilder {
+	qb.orders = append(qb.orders, OrderOption{Field: field, Direction: direction}.order())
+	return qb
+}
+
+// Limit sets the maximum number of records to return.
+func (qb *QueryBuilder) Limit(n int) *QueryBuilder {
+	qb.limit = n
+	return qb
+}
+
+// Offset sets the number of records to skip.
+func (qb *QueryBuilder) Offset(n int) *QueryBuilder {
+	qb.offset = n
+	return qb
+}
+
+// Columns sets the columns to fetch.
+func (qb *QueryBuilder) Columns(cols ...string) *QueryBuilder {
+	qb.selectCols = cols
+	return qb
+}
+
+// Params returns the PostgREST query parameters the query will be sent with.
+func (qb *QueryBuilder) Params() url.Values {
+	return qb.table().selectParams()
+}
+
+// table returns a Table carrying the builder's query state.
+func (qb *QueryBuilder) table() *Table {
+	return &Table{
+		client:     qb.client,
+		tableName:  qb.tableName,
+		filters:    qb.filters,
+		orders:     qb.orders,
+		limit:      qb.limit,
+		offset:     qb.offset,
+		selectCols: qb.selectCols,
+	}
+}
+
+// Execute runs the query and decodes the matching rows into a slice of T.
+func Execute[T any](qb *QueryBuilder, jwtToken string) ([]T, error) {
+	var rows []T
+	if err := qb.table().Select(&rows, jwtToken); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// filterOps are the PostgREST operators understood by ApplyFiltersFromURL.
+var filterOps = map[string]bool{
+	"eq": true, "neq": true, "gt": true, "lt": true, "gte": true, "lte": true,
+	"like": true, "ilike": true, "in": true, "is": true,
+}
+
+// StrictParsing makes ApplyFiltersFromURL return an error for query params it does not
+// understand instead of ignoring them.
+func (t *Table) StrictParsing(strict bool) *Table {
+	t.strictParsing = strict
+	return t
+}
+
+// ApplyFiltersFromURL applies PostgREST-style query params from u, such as
+// ?name=eq.Alice&age=gte.18&order=created_at.desc&limit=10&offset=20&select=id,name.
+// Filter values may be negated with "not." (e.g. status=not.eq.archived). Unknown params are
+// ignored unless StrictParsing is enabled; malformed limit, offset or order values are always errors.
+// Params are applied in key order, so the same URL always builds the same query.
+func (t *Table) ApplyFiltersFromURL(u *url.URL) (*Table, error) {
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range query[key] {
+			if err := t.applyURLParam(key, value); err != nil {
+				return t, err
+			}
+		}
+	}
+	return t, nil
+}
+
+func (t *Table) applyURLParam(key, value string) error {
+	switch key {
+	case "limit", "offset":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("supabase: invalid %s %q", key, value)
+		}
+		if key == "limit" {
+			t.Limit(n)
+		} else {
+			t.Offset(n)
+		}
+		return nil
+	case "select":
+		t.SelectColumns(strings.Split(value, ",")...)
+		return nil
+	case "order":
+		for _, term := range strings.Split(value, ",") {
+			parts := strings.Split(term, ".")
+			opt := OrderOption{Field: parts[0]}
+			for _, p := range parts[1:] {
+				switch p {
+				case "asc", "desc":
+					opt.Direction = p
+				case "nullsfirst":
+					opt.NullsFirst = true
+				case "nullslast":
+				default:
+					return fmt.Errorf("supabase: invalid order %q", term)
+				}
+			}
+			if opt.Field == "" {
+				return fmt.Errorf("supabase: invalid order %q", term)
+			}
+			t.Order(opt)
+		}
+		return nil
+	}
+
+	negate := strings.HasPrefix(value, "not.")
+	op, operand, ok := strings.Cut(strings.TrimPrefix(value, "not."), ".")
+	if !ok || !filterOps[op] {
+		if t.strictParsing {
+			return fmt.Errorf("supabase: unsupported query param %s=%s", key, value)
+		}
+		return nil
+	}
+	var f Filter = simpleFilter{key, op, operand}
+	if negate {
+		f = Not(f)
+	}
+	t.AddFilter(f)
+	return nil
+}
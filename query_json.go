@@ -0,0 +1,169 @@
+package supabasego
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// SerializedQuery is the JSON form of a Table's query, produced by Table.ToJSON and read by
+// Client.FromJSON. Each filter is an object of one of these shapes:
+//
+//	{"column": "age", "op": "gte", "value": 18}
+//	{"op": "or", "filters": [...]}           // also "and"
+//	{"op": "not", "filter": {...}}
+type SerializedQuery struct {
+	Table   string            `json:"table"`
+	Filters []json.RawMessage `json:"filters,omitempty"`
+	Orders  []OrderOption     `json:"orders,omitempty"`
+	Limit   int               `json:"limit,omitempty"`
+	Offset  int               `json:"offset,omitempty"`
+	Columns []string          `json:"columns,omitempty"`
+}
+
+// serializedFilter is the JSON form of a single filter.
+type serializedFilter struct {
+	Column  string            `json:"column,omitempty"`
+	Op      string            `json:"op"`
+	Value   json.RawMessage   `json:"value,omitempty"`
+	Filters []json.RawMessage `json:"filters,omitempty"`
+	Filter  json.RawMessage   `json:"filter,omitempty"`
+}
+
+// ToJSON serializes the table name, filters, ordering, pagination and selected columns so the
+// query can be stored, e.g. as a user's saved filter, and rebuilt later with Client.FromJSON.
+// Default filters set by TableWithDefaults are included like any other filter.
+func (t *Table) ToJSON() ([]byte, error) {
+	q := SerializedQuery{
+		Table:   t.tableName,
+		Limit:   t.limit,
+		Offset:  t.offset,
+		Columns: t.selectCols,
+	}
+	for _, f := range t.filters {
+		raw, err := marshalFilter(f)
+		if err != nil {
+			return nil, err
+		}
+		q.Filters = append(q.Filters, raw)
+	}
+	for _, o := range t.orders {
+		q.Orders = append(q.Orders, OrderOption{Field: o.field, Direction: o.direction, NullsFirst: o.nullsFirst, ForeignTable: o.foreignTable})
+	}
+	return json.Marshal(q)
+}
+
+func marshalFilter(f Filter) (json.RawMessage, error) {
+	var sf serializedFilter
+	switch f := f.(type) {
+	case simpleFilter:
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, fmt.Errorf("supabase: cannot serialize value of filter on %s: %w", f.field, err)
+		}
+		sf = serializedFilter{Column: f.field, Op: f.op, Value: value}
+	case groupFilter:
+		sf.Op = f.operator
+		for _, sub := range f.filters {
+			raw, err := marshalFilter(sub)
+			if err != nil {
+				return nil, err
+			}
+			sf.Filters = append(sf.Filters, raw)
+		}
+	case notFilter:
+		raw, err := marshalFilter(f.filter)
+		if err != nil {
+			return nil, err
+		}
+		sf = serializedFilter{Op: "not", Filter: raw}
+	case invalidFilter:
+		return nil, f.err
+	default:
+		return nil, fmt.Errorf("supabase: cannot serialize filter of type %T", f)
+	}
+	return json.Marshal(sf)
+}
+
+// FromJSON builds a Table from a query serialized with Table.ToJSON. Numbers in filter values
+// are kept exactly as written.
+func (c *Client) FromJSON(data []byte) (*Table, error) {
+	var q SerializedQuery
+	if err := json.Unmarshal(data, &q); err != nil {
+		return nil, fmt.Errorf("supabase: invalid serialized query: %w", err)
+	}
+	if q.Table == "" {
+		return nil, fmt.Errorf("supabase: invalid serialized query: missing table")
+	}
+	if q.Limit < 0 || q.Offset < 0 {
+		return nil, fmt.Errorf("supabase: invalid serialized query: negative limit or offset")
+	}
+	t := c.Table(q.Table)
+	for _, raw := range q.Filters {
+		f, err := unmarshalFilter(raw)
+		if err != nil {
+			return nil, err
+		}
+		t.AddFilter(f)
+	}
+	t.Order(q.Orders...)
+	t.Limit(q.Limit)
+	t.Offset(q.Offset)
+	if len(q.Columns) > 0 {
+		t.SelectColumns(q.Columns...)
+	}
+	return t, nil
+}
+
+func unmarshalFilter(raw json.RawMessage) (Filter, error) {
+	var sf serializedFilter
+	if err := json.Unmarshal(raw, &sf); err != nil {
+		return nil, fmt.Errorf("supabase: invalid serialized filter %s: %w", raw, err)
+	}
+	switch sf.Op {
+	case "and", "or":
+		filters := make([]Filter, len(sf.Filters))
+		for i, sub := range sf.Filters {
+			f, err := unmarshalFilter(sub)
+			if err != nil {
+				return nil, err
+			}
+			filters[i] = f
+		}
+		return groupFilter{sf.Op, filters}, nil
+	case "not":
+		if len(sf.Filter) == 0 {
+			return nil, fmt.Errorf("supabase: invalid serialized filter %s: missing filter", raw)
+		}
+		f, err := unmarshalFilter(sf.Filter)
+		if err != nil {
+			return nil, err
+		}
+		return Not(f), nil
+	}
+	if sf.Column == "" || !isOperator(sf.Op) {
+		return nil, fmt.Errorf("supabase: invalid serialized filter %s", raw)
+	}
+	var value interface{}
+	if len(sf.Value) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(sf.Value))
+		dec.UseNumber()
+		if err := dec.Decode(&value); err != nil {
+			return nil, fmt.Errorf("supabase: invalid serialized filter %s: %w", raw, err)
+		}
+	}
+	return simpleFilter{sf.Column, sf.Op, value}, nil
+}
+
+// isOperator reports whether op looks like a PostgREST operator name such as "eq" or "ilike".
+func isOperator(op string) bool {
+	if op == "" {
+		return false
+	}
+	for _, r := range op {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
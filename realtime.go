@@ -0,0 +1,996 @@
+package supabasego
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// realtimeHeartbeatInterval is the default interval between heartbeats that keep the
+// connection alive.
+const realtimeHeartbeatInterval = 30 * time.Second
+
+// realtimeMissedHeartbeats is how many consecutive heartbeats may go unacknowledged before the
+// connection is considered dead and re-established.
+const realtimeMissedHeartbeats = 2
+
+// realtimeJoinTimeout bounds each channel rejoin after a reconnect.
+const realtimeJoinTimeout = 10 * time.Second
+
+// realtimeMaxReconnectAttempts is the default number of reconnect attempts before giving up.
+const realtimeMaxReconnectAttempts = 10
+
+// realtimeReconnectBackoff is the wait before the second reconnect attempt; it doubles with each
+// further attempt up to realtimeMaxReconnectBackoff.
+const (
+	realtimeReconnectBackoff    = time.Second
+	realtimeMaxReconnectBackoff = 30 * time.Second
+)
+
+// realtimeLeaveTimeout is the default time RemoveAllChannels waits for leave acknowledgements.
+const realtimeLeaveTimeout = 10 * time.Second
+
+// RealtimeClient is a WebSocket connection to Supabase Realtime. Call Connect, create channels
+// with Channel, then Subscribe to them.
+type RealtimeClient struct {
+	client *Client
+	ref    atomic.Uint64
+
+	mu           sync.Mutex
+	conn         *wsConn
+	token        string
+	channels     map[string]*Channel        // by topic
+	pending      map[string]*realtimeWaiter // reply waiters by ref
+	done         chan struct{}              // closed when the read loop exits
+	err          error                      // why the read loop exited
+	status       ConnectionStatus
+	onStatus     []func(ConnectionStatus)
+	leaveTimeout time.Duration
+
+	heartbeatInterval time.Duration
+	heartbeatRef      string // ref of the last heartbeat, cleared when the server acknowledges it
+	gen               uint64 // incremented by Connect and Close so a stale reconnect gives up
+	closing           bool   // set by Close so a connection lost meanwhile is not re-established
+
+	maxReconnects    int
+	reconnectBackoff time.Duration
+	cancelReconnect  context.CancelFunc // stops a reconnect in progress; set while reconnecting
+	onMaxRetries     []func(error)
+
+	notifyMu sync.Mutex // serializes status callbacks so they see transitions in order
+}
+
+// ConnectionStatus is the state of a RealtimeClient's WebSocket connection.
+type ConnectionStatus int
+
+const (
+	StatusClosed ConnectionStatus = iota
+	StatusConnecting
+	StatusOpen
+	StatusClosing
+	StatusReconnecting
+)
+
+func (s ConnectionStatus) String() string {
+	switch s {
+	case StatusConnecting:
+		return "connecting"
+	case StatusOpen:
+		return "open"
+	case StatusClosing:
+		return "closing"
+	case StatusReconnecting:
+		return "reconnecting"
+	}
+	return "closed"
+}
+
+// realtimeMessage is a Phoenix channel message.
+type realtimeMessage struct {
+	Topic   string          `json:"topic"`
+	Event   string          `json:"event"`
+	Payload json.RawMessage `json:"payload"`
+	Ref     string          `json:"ref,omitempty"`
+}
+
+type realtimeReply struct {
+	Status   string          `json:"status"`
+	Response json.RawMessage `json:"response"`
+}
+
+// realtimeWaiter receives the reply to a push. onReply, if set, runs on the read goroutine
+// before any later message is dispatched.
+type realtimeWaiter struct {
+	reply   chan realtimeReply
+	onReply func(realtimeReply)
+}
+
+// RealtimeConfig holds optional settings for a RealtimeClient.
+type RealtimeConfig struct {
+	// HeartbeatInterval is how often a heartbeat is sent to keep idle connections from being
+	// dropped by proxies and load balancers. Defaults to 30 seconds.
+	HeartbeatInterval time.Duration
+}
+
+// Realtime returns a RealtimeClient for the Supabase Realtime API. Channels join with the
+// client's API key as access token.
+func (c *Client) Realtime() *RealtimeClient {
+	return c.RealtimeWithConfig(RealtimeConfig{})
+}
+
+// RealtimeWithConfig is like Realtime but applies cfg.
+func (c *Client) RealtimeWithConfig(cfg RealtimeConfig) *RealtimeClient {
+	r := &RealtimeClient{
+		client:   c,
+		token:    c.APIKey,
+		channels: map[string]*Channel{},
+		pending:  map[string]*realtimeWaiter{},
+
+		leaveTimeout:      realtimeLeaveTimeout,
+		heartbeatInterval: realtimeHeartbeatInterval,
+		maxReconnects:     realtimeMaxReconnectAttempts,
+		reconnectBackoff:  realtimeReconnectBackoff,
+	}
+	r.SetHeartbeatInterval(cfg.HeartbeatInterval)
+	return r
+}
+
+// url returns the WebSocket endpoint derived from the client's BaseURL.
+func (r *RealtimeClient) url() string {
+	base := r.client.BaseURL
+	if strings.HasPrefix(base, "https://") {
+		base = "wss://" + strings.TrimPrefix(base, "https://")
+	} else if strings.HasPrefix(base, "http://") {
+		base = "ws://" + strings.TrimPrefix(base, "http://")
+	}
+	params := url.Values{"apikey": {r.client.APIKey}, "vsn": {"1.0.0"}}
+	return base + REALTIME_URL + "/websocket?" + params.Encode()
+}
+
+// Connect opens the WebSocket connection. A heartbeat is then sent every heartbeat interval
+// (see SetHeartbeatInterval). If the connection is lost, or two heartbeats in a row go
+// unacknowledged, it is re-established and the channels that were joined are joined again,
+// with the status going through StatusReconnecting. See SetMaxReconnectAttempts for how long it
+// keeps trying.
+func (r *RealtimeClient) Connect(ctx context.Context) error {
+	r.mu.Lock()
+	r.gen++
+	gen := r.gen
+	r.closing = false
+	if r.cancelReconnect != nil {
+		r.cancelReconnect()
+		r.cancelReconnect = nil
+	}
+	r.mu.Unlock()
+	r.setStatus(StatusConnecting)
+	if err := r.open(ctx, gen); err != nil {
+		r.setStatus(StatusClosed)
+		return err
+	}
+	return nil
+}
+
+// open dials the server and starts the read and heartbeat loops, unless Connect or Close has
+// been called since gen was taken.
+func (r *RealtimeClient) open(ctx context.Context, gen uint64) error {
+	conn, err := dialWebSocket(ctx, r.url())
+	if err != nil {
+		return fmt.Errorf("realtime connect failed: %w", err)
+	}
+	done := make(chan struct{})
+	r.mu.Lock()
+	if r.gen != gen {
+		r.mu.Unlock()
+		conn.close()
+		return ErrNotConnected
+	}
+	r.conn = conn
+	r.done = done
+	r.err = nil
+	r.heartbeatRef = ""
+	r.mu.Unlock()
+	r.setStatus(StatusOpen)
+	go r.readLoop(conn, done)
+	go r.heartbeatLoop(conn, done)
+	return nil
+}
+
+// SetHeartbeatInterval sets how often a heartbeat is sent. Zero or less restores the default of
+// 30 seconds. A running connection picks up the new interval after its next heartbeat.
+func (r *RealtimeClient) SetHeartbeatInterval(d time.Duration) {
+	if d <= 0 {
+		d = realtimeHeartbeatInterval
+	}
+	r.mu.Lock()
+	r.heartbeatInterval = d
+	r.mu.Unlock()
+}
+
+// SetMaxReconnectAttempts sets how many times the client tries to re-establish a dead
+// connection (see Connect) before giving up; the default is 10. Attempts are spaced with
+// exponential backoff, starting at one second and capped at 30 seconds. Zero or less disables
+// reconnecting.
+func (r *RealtimeClient) SetMaxReconnectAttempts(n int) {
+	r.mu.Lock()
+	r.maxReconnects = n
+	r.mu.Unlock()
+}
+
+// OnMaxRetriesExceeded registers cb to be called when the client gives up reconnecting, with
+// the error of the last attempt. By then every channel has been closed and removed and the
+// status is StatusClosed; call Connect to start over.
+func (r *RealtimeClient) OnMaxRetriesExceeded(cb func(err error)) {
+	r.mu.Lock()
+	r.onMaxRetries = append(r.onMaxRetries, cb)
+	r.mu.Unlock()
+}
+
+// Close leaves every channel (see RemoveAllChannels) and closes the connection.
+func (r *RealtimeClient) Close() error {
+	r.mu.Lock()
+	r.gen++
+	r.closing = true
+	connected := r.conn != nil
+	if r.cancelReconnect != nil {
+		r.cancelReconnect()
+		r.cancelReconnect = nil
+	}
+	r.mu.Unlock()
+	if !connected {
+		if r.Status() == StatusReconnecting {
+			// Stop a reconnect in progress.
+			r.setChannelStates(ChannelClosed)
+			r.setStatus(StatusClosed)
+		}
+		return nil
+	}
+	r.setStatus(StatusClosing)
+	leaveErr := r.RemoveAllChannels()
+	r.mu.Lock()
+	conn := r.conn
+	r.conn = nil
+	r.mu.Unlock()
+	if conn == nil {
+		// The connection was lost while leaving.
+		return leaveErr
+	}
+	err := conn.close()
+	r.setChannelStates(ChannelClosed)
+	r.setStatus(StatusClosed)
+	return errors.Join(leaveErr, err)
+}
+
+// RemoveAllChannels leaves every subscribed channel, waiting up to the leave timeout (10s by
+// default, see SetLeaveTimeout) for the server to acknowledge, and removes all channels from
+// the client so the server does not keep them open. Close calls it automatically.
+func (r *RealtimeClient) RemoveAllChannels() error {
+	r.mu.Lock()
+	connected := r.conn != nil
+	timeout := r.leaveTimeout
+	r.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	channels := r.Channels()
+	errs := make([]error, len(channels))
+	var wg sync.WaitGroup
+	for i, ch := range channels {
+		if s := ch.State(); connected && (s == ChannelSubscribing || s == ChannelJoined) {
+			wg.Add(1)
+			go func(i int, ch *Channel) {
+				defer wg.Done()
+				if err := ch.Unsubscribe(ctx); err != nil {
+					errs[i] = fmt.Errorf("leave %s: %w", ch.Name(), err)
+				}
+			}(i, ch)
+			continue
+		}
+		ch.setState(ChannelClosed)
+		r.mu.Lock()
+		delete(r.channels, ch.topic)
+		r.mu.Unlock()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// SetLeaveTimeout sets how long RemoveAllChannels (and so Close) waits for the server to
+// acknowledge leaving the channels.
+func (r *RealtimeClient) SetLeaveTimeout(d time.Duration) {
+	r.mu.Lock()
+	r.leaveTimeout = d
+	r.mu.Unlock()
+}
+
+// ChannelCount returns the number of channels the client holds.
+func (r *RealtimeClient) ChannelCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.channels)
+}
+
+// setChannelStates moves every subscribing or joined channel to state, e.g. when the
+// connection closes.
+func (r *RealtimeClient) setChannelStates(state ChannelState) {
+	for _, ch := range r.Channels() {
+		if s := ch.State(); s == ChannelSubscribing || s == ChannelJoined {
+			ch.setState(state)
+		}
+	}
+}
+
+// Status returns the current connection status.
+func (r *RealtimeClient) Status() ConnectionStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+// OnStatusChange registers cb to be called on every connection status transition, e.g. to show
+// a connectivity indicator. Callbacks run one at a time, in order, and must not call Connect or
+// Close.
+func (r *RealtimeClient) OnStatusChange(cb func(status ConnectionStatus)) {
+	r.mu.Lock()
+	r.onStatus = append(r.onStatus, cb)
+	r.mu.Unlock()
+}
+
+// setStatus records a status transition and notifies the OnStatusChange callbacks.
+func (r *RealtimeClient) setStatus(status ConnectionStatus) {
+	r.notifyMu.Lock()
+	defer r.notifyMu.Unlock()
+	r.mu.Lock()
+	if r.status == status {
+		r.mu.Unlock()
+		return
+	}
+	r.status = status
+	callbacks := make([]func(ConnectionStatus), len(r.onStatus))
+	copy(callbacks, r.onStatus)
+	r.mu.Unlock()
+	for _, cb := range callbacks {
+		cb(status)
+	}
+}
+
+// SetAuth replaces the access token used by the connection, e.g. after the user's JWT has
+// been refreshed. Channels joined later use the new token, and every joined channel is sent
+// the new token so its authorization does not lapse when the old JWT expires. Call it whenever
+// the session is refreshed.
+func (r *RealtimeClient) SetAuth(token string) error {
+	r.mu.Lock()
+	r.token = token
+	conn := r.conn
+	var joined []*Channel
+	for _, ch := range r.channels {
+		if ch.State() == ChannelJoined {
+			joined = append(joined, ch)
+		}
+	}
+	r.mu.Unlock()
+	if conn == nil {
+		return nil
+	}
+	payload := map[string]string{"access_token": token}
+	for _, ch := range joined {
+		if err := r.send(conn, ch.topic, "access_token", payload, r.nextRef()); err != nil {
+			return fmt.Errorf("realtime set auth failed: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *RealtimeClient) readLoop(conn *wsConn, done chan struct{}) {
+	for {
+		data, err := conn.readMessage()
+		if err != nil {
+			r.mu.Lock()
+			r.err = err
+			for ref, w := range r.pending {
+				close(w.reply)
+				delete(r.pending, ref)
+			}
+			r.mu.Unlock()
+			close(done)
+			// Unless Close was called or conn has already been given up on, the connection
+			// was lost.
+			r.reconnect(conn)
+			return
+		}
+		var msg realtimeMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		r.dispatch(msg)
+	}
+}
+
+// heartbeatLoop sends a heartbeat every heartbeat interval and reconnects once
+// realtimeMissedHeartbeats in a row have not been acknowledged.
+func (r *RealtimeClient) heartbeatLoop(conn *wsConn, done chan struct{}) {
+	missed := 0
+	for {
+		r.mu.Lock()
+		interval := r.heartbeatInterval
+		r.mu.Unlock()
+		timer := time.NewTimer(interval)
+		select {
+		case <-done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		ref := r.nextRef()
+		r.mu.Lock()
+		if r.conn != conn {
+			r.mu.Unlock()
+			return
+		}
+		if r.heartbeatRef != "" {
+			missed++
+		} else {
+			missed = 0
+		}
+		r.heartbeatRef = ref
+		r.mu.Unlock()
+		if missed >= realtimeMissedHeartbeats {
+			r.reconnect(conn)
+			return
+		}
+		if err := r.send(conn, "phoenix", "heartbeat", struct{}{}, ref); err != nil {
+			return
+		}
+	}
+}
+
+// reconnect replaces conn, which has been lost or has stopped answering heartbeats, with a new
+// connection and rejoins the channels that were joined, making up to the maximum number of
+// attempts. It does nothing if conn has already been closed or replaced, and stops if Connect
+// or Close is called meanwhile.
+func (r *RealtimeClient) reconnect(conn *wsConn) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.mu.Lock()
+	if r.conn != conn || r.closing {
+		r.mu.Unlock()
+		return
+	}
+	// Clearing conn first makes the read loop treat the close below as deliberate.
+	r.conn = nil
+	done := r.done
+	gen := r.gen
+	attempts := r.maxReconnects
+	backoff := r.reconnectBackoff
+	r.cancelReconnect = cancel
+	r.mu.Unlock()
+
+	r.setStatus(StatusReconnecting)
+	var rejoin []*Channel
+	for _, ch := range r.Channels() {
+		if s := ch.State(); s == ChannelSubscribing || s == ChannelJoined {
+			rejoin = append(rejoin, ch)
+		}
+	}
+	r.setChannelStates(ChannelErrored)
+	conn.close()
+	// Wait for the old read loop to fail its pending pushes before new ones are made.
+	<-done
+
+	err := ErrNotConnected
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(reconnectWait(backoff, attempt)):
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err = r.open(ctx, gen); err == nil || errors.Is(err, ErrNotConnected) || ctx.Err() != nil {
+			break
+		}
+	}
+	r.mu.Lock()
+	if r.gen == gen {
+		r.cancelReconnect = nil
+	}
+	closing := r.closing
+	callbacks := make([]func(error), len(r.onMaxRetries))
+	copy(callbacks, r.onMaxRetries)
+	r.mu.Unlock()
+	if ctx.Err() != nil || (errors.Is(err, ErrNotConnected) && attempts > 0) {
+		// Superseded by Connect or Close. Close may have looked before the status moved to
+		// StatusReconnecting, so finish closing here.
+		if closing {
+			r.setChannelStates(ChannelClosed)
+			r.setStatus(StatusClosed)
+		}
+		return
+	}
+	if err != nil {
+		r.RemoveAllChannels()
+		r.setStatus(StatusClosed)
+		err = fmt.Errorf("supabase: realtime reconnect failed after %d attempts: %w", attempts, err)
+		for _, cb := range callbacks {
+			cb(err)
+		}
+		return
+	}
+	for _, ch := range rejoin {
+		ctx, cancel := context.WithTimeout(context.Background(), realtimeJoinTimeout)
+		ch.Subscribe(ctx)
+		cancel()
+	}
+}
+
+// reconnectWait returns the wait before the given reconnect attempt, counting from 0: none
+// before the first, then backoff doubling with each attempt up to realtimeMaxReconnectBackoff.
+// It stops doubling at the cap rather than shifting, which would overflow after enough attempts.
+func reconnectWait(backoff time.Duration, attempt int) time.Duration {
+	if attempt == 0 {
+		return 0
+	}
+	wait := min(backoff, realtimeMaxReconnectBackoff)
+	for i := 1; i < attempt && wait > 0 && wait < realtimeMaxReconnectBackoff; i++ {
+		wait = min(wait*2, realtimeMaxReconnectBackoff)
+	}
+	return wait
+}
+
+func (r *RealtimeClient) dispatch(msg realtimeMessage) {
+	r.mu.Lock()
+	if msg.Event == "phx_reply" && msg.Ref != "" {
+		if msg.Topic == "phoenix" && msg.Ref == r.heartbeatRef {
+			r.heartbeatRef = ""
+		}
+		w, ok := r.pending[msg.Ref]
+		delete(r.pending, msg.Ref)
+		r.mu.Unlock()
+		var reply realtimeReply
+		if ok && json.Unmarshal(msg.Payload, &reply) == nil {
+			if w.onReply != nil && reply.Status == "ok" {
+				w.onReply(reply)
+			}
+			w.reply <- reply
+		} else if ok {
+			close(w.reply)
+		}
+		return
+	}
+	ch := r.channels[msg.Topic]
+	r.mu.Unlock()
+	if ch != nil {
+		ch.dispatch(msg)
+	}
+}
+
+func (r *RealtimeClient) nextRef() string {
+	return strconv.FormatUint(r.ref.Add(1), 10)
+}
+
+func (r *RealtimeClient) send(conn *wsConn, topic, event string, payload interface{}, ref string) error {
+	p, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(realtimeMessage{Topic: topic, Event: event, Payload: p, Ref: ref})
+	if err != nil {
+		return err
+	}
+	return conn.writeText(b)
+}
+
+// push sends a message and waits for the server's reply, returning the reply's response.
+// onReply, if non-nil, is applied to a successful reply before later messages are dispatched.
+func (r *RealtimeClient) push(ctx context.Context, topic, event string, payload interface{}, onReply func(realtimeReply)) (json.RawMessage, error) {
+	ref := r.nextRef()
+	w := &realtimeWaiter{reply: make(chan realtimeReply, 1), onReply: onReply}
+	r.mu.Lock()
+	conn := r.conn
+	if conn == nil || r.err != nil {
+		r.mu.Unlock()
+		return nil, ErrNotConnected
+	}
+	r.pending[ref] = w
+	r.mu.Unlock()
+
+	if err := r.send(conn, topic, event, payload, ref); err != nil {
+		r.forget(ref)
+		return nil, fmt.Errorf("realtime %s failed: %w", event, err)
+	}
+	select {
+	case reply, ok := <-w.reply:
+		if !ok {
+			return nil, ErrNotConnected
+		}
+		if reply.Status != "ok" {
+			return nil, fmt.Errorf("supabase: realtime %s failed: %s", event, string(reply.Response))
+		}
+		return reply.Response, nil
+	case <-ctx.Done():
+		r.forget(ref)
+		return nil, ctx.Err()
+	}
+}
+
+func (r *RealtimeClient) forget(ref string) {
+	r.mu.Lock()
+	delete(r.pending, ref)
+	r.mu.Unlock()
+}
+
+// Channel is a Realtime channel. Register handlers before calling Subscribe.
+type Channel struct {
+	rt    *RealtimeClient
+	topic string
+
+	mu       sync.Mutex
+	bindings []*postgresChangesBinding
+	state    ChannelState
+	onState  []func(old, new ChannelState)
+
+	notifyMu sync.Mutex // serializes state callbacks so they see transitions in order
+}
+
+// ChannelState is the lifecycle state of a Channel.
+type ChannelState int
+
+const (
+	ChannelClosed      ChannelState = iota // Not subscribed
+	ChannelSubscribing                     // Join sent, waiting for the server
+	ChannelJoined                          // Subscribed and receiving events
+	ChannelLeaving                         // Leave sent, waiting for the server
+	ChannelErrored                         // Join failed or the connection was lost
+)
+
+func (s ChannelState) String() string {
+	switch s {
+	case ChannelSubscribing:
+		return "subscribing"
+	case ChannelJoined:
+		return "joined"
+	case ChannelLeaving:
+		return "leaving"
+	case ChannelErrored:
+		return "errored"
+	}
+	return "closed"
+}
+
+// State returns the channel's current state.
+func (ch *Channel) State() ChannelState {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return ch.state
+}
+
+// OnStateChange registers cb to be called on every state transition of the channel. Callbacks
+// run one at a time, in order, and must not call Subscribe or Unsubscribe.
+func (ch *Channel) OnStateChange(cb func(old, new ChannelState)) *Channel {
+	ch.mu.Lock()
+	ch.onState = append(ch.onState, cb)
+	ch.mu.Unlock()
+	return ch
+}
+
+// setState records a state transition and notifies the OnStateChange callbacks.
+func (ch *Channel) setState(state ChannelState) {
+	ch.notifyMu.Lock()
+	defer ch.notifyMu.Unlock()
+	ch.mu.Lock()
+	old := ch.state
+	if old == state {
+		ch.mu.Unlock()
+		return
+	}
+	ch.state = state
+	callbacks := make([]func(old, new ChannelState), len(ch.onState))
+	copy(callbacks, ch.onState)
+	ch.mu.Unlock()
+	for _, cb := range callbacks {
+		cb(old, state)
+	}
+}
+
+// PostgresChangesFilter selects which database changes a channel receives.
+type PostgresChangesFilter struct {
+	Event  string // "INSERT", "UPDATE", "DELETE" or "*" (default)
+	Schema string // Defaults to "public"
+	Table  string // Empty matches all tables in the schema
+	Filter string // Optional row filter, e.g. "tenant_id=eq.42"
+}
+
+// RealtimePostgresChange is a row change delivered by Realtime.
+type RealtimePostgresChange struct {
+	Schema          string                 `json:"schema"`
+	Table           string                 `json:"table"`
+	EventType       string                 `json:"type"` // INSERT, UPDATE or DELETE
+	CommitTimestamp string                 `json:"commit_timestamp"`
+	New             map[string]interface{} `json:"record,omitempty"`
+	Old             map[string]interface{} `json:"old_record,omitempty"`
+}
+
+type postgresChangesBinding struct {
+	filter  PostgresChangesFilter
+	id      int64 // Assigned by the server on join
+	handler func(RealtimePostgresChange)
+}
+
+// Channel returns the channel with the given name, creating it if needed.
+func (r *RealtimeClient) Channel(name string) *Channel {
+	topic := "realtime:" + name
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ch, ok := r.channels[topic]; ok {
+		return ch
+	}
+	ch := &Channel{rt: r, topic: topic}
+	r.channels[topic] = ch
+	return ch
+}
+
+// GetChannel returns the channel with the given name and true if it has been created with
+// Channel (and not since unsubscribed), or nil and false otherwise.
+func (r *RealtimeClient) GetChannel(name string) (*Channel, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ch, ok := r.channels["realtime:"+name]
+	return ch, ok
+}
+
+// Channels returns the client's channels, ordered by name.
+func (r *RealtimeClient) Channels() []*Channel {
+	r.mu.Lock()
+	channels := make([]*Channel, 0, len(r.channels))
+	for _, ch := range r.channels {
+		channels = append(channels, ch)
+	}
+	r.mu.Unlock()
+	sort.Slice(channels, func(i, j int) bool { return channels[i].topic < channels[j].topic })
+	return channels
+}
+
+// Name returns the name the channel was created with.
+func (ch *Channel) Name() string {
+	return strings.TrimPrefix(ch.topic, "realtime:")
+}
+
+// OnPostgresChanges registers handler for database changes matching filter. Handlers are called
+// from the connection's read goroutine and should return quickly.
+func (ch *Channel) OnPostgresChanges(filter PostgresChangesFilter, handler func(RealtimePostgresChange)) *Channel {
+	if filter.Event == "" {
+		filter.Event = "*"
+	}
+	if filter.Schema == "" {
+		filter.Schema = "public"
+	}
+	ch.mu.Lock()
+	ch.bindings = append(ch.bindings, &postgresChangesBinding{filter: filter, handler: handler})
+	ch.mu.Unlock()
+	return ch
+}
+
+type postgresChangesConfig struct {
+	ID     int64  `json:"id,omitempty"`
+	Event  string `json:"event"`
+	Schema string `json:"schema"`
+	Table  string `json:"table,omitempty"`
+	Filter string `json:"filter,omitempty"`
+}
+
+// Subscribe joins the channel and waits for the server to confirm.
+func (ch *Channel) Subscribe(ctx context.Context) error {
+	ch.mu.Lock()
+	changes := make([]postgresChangesConfig, len(ch.bindings))
+	for i, b := range ch.bindings {
+		changes[i] = postgresChangesConfig{Event: b.filter.Event, Schema: b.filter.Schema, Table: b.filter.Table, Filter: b.filter.Filter}
+	}
+	ch.mu.Unlock()
+
+	ch.rt.mu.Lock()
+	token := ch.rt.token
+	ch.rt.mu.Unlock()
+	payload := map[string]interface{}{
+		"config": map[string]interface{}{
+			"broadcast":        map[string]interface{}{"ack": false, "self": false},
+			"presence":         map[string]interface{}{"key": ""},
+			"postgres_changes": changes,
+		},
+		"access_token": token,
+	}
+	ch.setState(ChannelSubscribing)
+	if _, err := ch.rt.push(ctx, ch.topic, "phx_join", payload, ch.onJoined); err != nil {
+		ch.setState(ChannelErrored)
+		return err
+	}
+	return nil
+}
+
+// onJoined marks the channel joined and records the ids the server assigned to the
+// postgres_changes bindings, which it echoes in the join reply in the order they were requested.
+func (ch *Channel) onJoined(reply realtimeReply) {
+	var joined struct {
+		PostgresChanges []postgresChangesConfig `json:"postgres_changes"`
+	}
+	if err := json.Unmarshal(reply.Response, &joined); err == nil {
+		ch.mu.Lock()
+		for i, b := range ch.bindings {
+			if i < len(joined.PostgresChanges) {
+				b.id = joined.PostgresChanges[i].ID
+			}
+		}
+		ch.mu.Unlock()
+	}
+	ch.setState(ChannelJoined)
+}
+
+// Unsubscribe leaves the channel and removes it from the client.
+func (ch *Channel) Unsubscribe(ctx context.Context) error {
+	ch.setState(ChannelLeaving)
+	_, err := ch.rt.push(ctx, ch.topic, "phx_leave", struct{}{}, nil)
+	ch.setState(ChannelClosed)
+	ch.rt.mu.Lock()
+	delete(ch.rt.channels, ch.topic)
+	ch.rt.mu.Unlock()
+	return err
+}
+
+// Broadcast sends a broadcast message to the other clients subscribed to the channel.
+// Returns ErrChannelNotJoined unless the channel is joined.
+func (ch *Channel) Broadcast(event string, payload interface{}) error {
+	if ch.State() != ChannelJoined {
+		return ErrChannelNotJoined
+	}
+	ch.rt.mu.Lock()
+	conn := ch.rt.conn
+	ch.rt.mu.Unlock()
+	if conn == nil {
+		return ErrNotConnected
+	}
+	msg := map[string]interface{}{"type": "broadcast", "event": event, "payload": payload}
+	if err := ch.rt.send(conn, ch.topic, "broadcast", msg, ch.rt.nextRef()); err != nil {
+		return fmt.Errorf("realtime broadcast failed: %w", err)
+	}
+	return nil
+}
+
+func (ch *Channel) dispatch(msg realtimeMessage) {
+	switch msg.Event {
+	case "phx_error":
+		ch.setState(ChannelErrored)
+		return
+	case "phx_close":
+		ch.setState(ChannelClosed)
+		return
+	case "postgres_changes":
+	default:
+		return
+	}
+	var payload struct {
+		IDs  []int64                `json:"ids"`
+		Data RealtimePostgresChange `json:"data"`
+	}
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return
+	}
+	ch.mu.Lock()
+	var handlers []func(RealtimePostgresChange)
+	for _, b := range ch.bindings {
+		if b.matches(payload.IDs, payload.Data.EventType) {
+			handlers = append(handlers, b.handler)
+		}
+	}
+	ch.mu.Unlock()
+	for _, h := range handlers {
+		h(payload.Data)
+	}
+}
+
+func (b *postgresChangesBinding) matches(ids []int64, eventType string) bool {
+	if len(ids) == 0 {
+		return b.filter.Event == "*" || b.filter.Event == eventType
+	}
+	for _, id := range ids {
+		if id == b.id {
+			return true
+		}
+	}
+	return false
+}
+
+// WatchTable subscribes to changes on schema.table and calls handler for each one until ctx is
+// cancelled. events may contain "INSERT", "UPDATE", "DELETE" or "*" (empty means "*").
+// Each call to handler runs in its own goroutine, so a slow handler does not hold up the
+// connection, but calls may run concurrently and finish out of commit order. A lost connection
+// is re-established and the table watched again as described for Connect. WatchTable blocks;
+// it returns nil once ctx is cancelled, or an error if the connection cannot be established,
+// reconnecting gives up or the channel cannot be rejoined, in each case after every handler
+// call has returned.
+func (c *Client) WatchTable(ctx context.Context, schema, table string, events []string, handler func(RealtimePostgresChange)) error {
+	if len(events) == 0 {
+		events = []string{"*"}
+	}
+	for _, e := range events {
+		switch e {
+		case "INSERT", "UPDATE", "DELETE":
+		case "*":
+			events = []string{"*"}
+		default:
+			return fmt.Errorf("supabase: invalid realtime event %q", e)
+		}
+	}
+	return c.Realtime().watchTable(ctx, schema, table, events, handler)
+}
+
+// watchTable implements WatchTable on r, which it connects and closes.
+func (r *RealtimeClient) watchTable(ctx context.Context, schema, table string, events []string, handler func(RealtimePostgresChange)) error {
+	if err := r.Connect(ctx); err != nil {
+		return err
+	}
+	defer r.Close()
+
+	changes := make(chan RealtimePostgresChange, 64)
+	ch := r.Channel("watch:" + schema + ":" + table)
+	for _, e := range events {
+		ch.OnPostgresChanges(PostgresChangesFilter{Event: e, Schema: schema, Table: table}, func(change RealtimePostgresChange) {
+			select {
+			case changes <- change:
+			case <-ctx.Done():
+			}
+		})
+	}
+
+	// A lost connection is re-established and the channel rejoined by reconnect, so the watch
+	// only ends when reconnecting gives up or the channel errors on a healthy connection, e.g.
+	// because the rejoin was refused.
+	lost := make(chan error, 1)
+	fail := func(err error) {
+		select {
+		case lost <- err:
+		default:
+		}
+	}
+	r.OnMaxRetriesExceeded(func(err error) {
+		fail(fmt.Errorf("realtime connection lost: %w", err))
+	})
+	ch.OnStateChange(func(old, state ChannelState) {
+		r.mu.Lock()
+		healthy := r.status == StatusOpen && r.err == nil
+		r.mu.Unlock()
+		if state == ChannelErrored && healthy {
+			fail(fmt.Errorf("supabase: realtime channel %s errored", ch.Name()))
+		}
+	})
+
+	if err := ch.Subscribe(ctx); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+
+	var handlers sync.WaitGroup
+	defer handlers.Wait()
+	for {
+		select {
+		case change := <-changes:
+			handlers.Add(1)
+			go func() {
+				defer handlers.Done()
+				handler(change)
+			}()
+		case <-ctx.Done():
+			return nil
+		case err := <-lost:
+			return err
+		}
+	}
+}
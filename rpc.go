@@ -0,0 +1,46 @@
+package supabasego
+
+import (
+	"context"
+	"net/url"
+)
+
+// RPCRequest is a call to a PostgreSQL function exposed by PostgREST. Create one with
+// Client.RPC and run it with Execute or ExecuteCtx.
+type RPCRequest struct {
+	client   *Client
+	fn       string
+	params   interface{}
+	jwtToken string
+}
+
+// RPC prepares a call to the PostgreSQL function fn with params (a struct or map marshalled to
+// JSON as the function's named arguments; nil for none).
+func (c *Client) RPC(fn string, params interface{}, jwtToken string) *RPCRequest {
+	return &RPCRequest{client: c, fn: fn, params: params, jwtToken: jwtToken}
+}
+
+// Execute calls the function and decodes its JSON result into dest (skipped when dest is nil).
+func (r *RPCRequest) Execute(dest interface{}) error {
+	return r.ExecuteCtx(context.Background(), dest)
+}
+
+// ExecuteCtx is Execute with a context that cancels the HTTP call. If the function raises an
+// exception, the returned *APIError carries its SQLSTATE in Code along with Message, Details
+// and Hint:
+//
+//	var apiErr *supabasego.APIError
+//	if errors.As(err, &apiErr) && apiErr.Code == "P0001" {
+//		// handle the function's RAISE EXCEPTION
+//	}
+func (r *RPCRequest) ExecuteCtx(ctx context.Context, dest interface{}) error {
+	params := r.params
+	if params == nil {
+		params = struct{}{}
+	}
+	req, err := r.client.newRequest("POST", REST_URL+"/rpc/"+url.PathEscape(r.fn), params, r.jwtToken)
+	if err != nil {
+		return err
+	}
+	return r.client.doJSON(req.WithContext(ctx), "rpc "+r.fn, dest)
+}
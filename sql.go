@@ -0,0 +1,162 @@
+package supabasego
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sqlOperators maps PostgREST operators to their SQL equivalents.
+var sqlOperators = map[string]string{
+	"eq": "=", "neq": "<>", "gt": ">", "gte": ">=", "lt": "<", "lte": "<=",
+	"like": "LIKE", "ilike": "ILIKE", "is": "IS", "in": "IN", "cs": "@>", "cd": "<@",
+}
+
+// ToSQL returns a human-readable approximation of the SQL the query will run, e.g.
+//
+//	SELECT id, name FROM tenants WHERE plan = 'pro' AND age > 18 ORDER BY created_at DESC LIMIT 10 OFFSET 20
+//
+// It makes no request and only reflects the filters, ordering, pagination and columns set on
+// the table. PostgREST builds the real statement itself, so use it for debugging and display
+// rather than execution.
+func (t *Table) ToSQL() string {
+	var b strings.Builder
+	cols := "*"
+	if len(t.selectCols) > 0 {
+		cols = strings.Join(t.selectCols, ", ")
+	}
+	fmt.Fprintf(&b, "SELECT %s FROM %s", cols, t.tableName)
+	if len(t.filters) > 0 {
+		conds := make([]string, len(t.filters))
+		for i, f := range t.filters {
+			conds[i] = filterSQL(f)
+		}
+		b.WriteString(" WHERE " + strings.Join(conds, " AND "))
+	}
+	if len(t.orders) > 0 {
+		terms := make([]string, len(t.orders))
+		for i, o := range t.orders {
+			terms[i] = o.sql()
+		}
+		b.WriteString(" ORDER BY " + strings.Join(terms, ", "))
+	}
+	if t.limit > 0 {
+		fmt.Fprintf(&b, " LIMIT %d", t.limit)
+	}
+	if t.offset > 0 {
+		fmt.Fprintf(&b, " OFFSET %d", t.offset)
+	}
+	return b.String()
+}
+
+// sql renders the order as an ORDER BY term.
+func (o order) sql() string {
+	s := o.field + " " + strings.ToUpper(o.direction)
+	if o.foreignTable != "" {
+		s = o.foreignTable + "." + s
+	}
+	if o.nullsFirst {
+		s += " NULLS FIRST"
+	} else if o.nullsLast {
+		s += " NULLS LAST"
+	}
+	return s
+}
+
+// filterSQL renders a filter as a SQL condition.
+func filterSQL(f Filter) string {
+	switch f := f.(type) {
+	case simpleFilter:
+		return simpleFilterSQL(f)
+	case groupFilter:
+		conds := make([]string, len(f.filters))
+		for i, inner := range f.filters {
+			conds[i] = filterSQL(inner)
+		}
+		return "(" + strings.Join(conds, " "+strings.ToUpper(f.operator)+" ") + ")"
+	case notFilter:
+		return "NOT (" + filterSQL(f.filter) + ")"
+	}
+	return f.toQuery()
+}
+
+func simpleFilterSQL(f simpleFilter) string {
+	if isNilValue(f.value) {
+		if f.op == "neq" {
+			return f.field + " IS NOT NULL"
+		}
+		return f.field + " IS NULL"
+	}
+	op, ok := sqlOperators[f.op]
+	if !ok {
+		op = f.op
+	}
+	switch f.op {
+	case "in":
+		// The value is already a PostgREST list such as (1,"a b").
+		list := strings.TrimSuffix(strings.TrimPrefix(fmt.Sprint(f.value), "("), ")")
+		var items []string
+		if list != "" {
+			for _, v := range splitInList(list) {
+				items = append(items, listItemSQL(v))
+			}
+		}
+		return fmt.Sprintf("%s IN (%s)", f.field, strings.Join(items, ", "))
+	case "is":
+		return fmt.Sprintf("%s IS %s", f.field, strings.ToUpper(fmt.Sprint(f.value)))
+	}
+	return fmt.Sprintf("%s %s %s", f.field, op, sqlLiteral(f.value))
+}
+
+// listItemSQL renders one value of a PostgREST list, leaving numbers and null unquoted.
+func listItemSQL(v string) string {
+	if v == "null" {
+		return "NULL"
+	}
+	if _, err := strconv.ParseFloat(v, 64); err == nil {
+		return v
+	}
+	return sqlQuote(v)
+}
+
+// sqlLiteral renders a filter value as a SQL literal.
+func sqlLiteral(v interface{}) string {
+	switch vv := v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(vv)
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(vv))
+	case time.Time:
+		return sqlQuote(vv.Format(time.RFC3339Nano))
+	}
+	return sqlQuote(fmt.Sprint(v))
+}
+
+// sqlQuote wraps s in single quotes, doubling any single quotes inside it.
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// JSONBSet returns an update map that sets one nested field of a JSONB column, for use with
+// Update, instead of fetching and re-sending the whole document:
+//
+//	client.Table("users").Eq("id", id).Update(supabasego.JSONBSet("settings", "theme.color", "dark"), nil, jwt)
+//
+// path is dot-separated, and value is marshalled to JSON (a value that cannot be marshalled
+// becomes null). The map holds the raw expression
+// column = jsonb_set(column, '{theme,color}', '"dark"'). Plain PostgREST stores update values
+// as literals and never evaluates expressions, so this needs a custom setup that does, such as a
+// BEFORE UPDATE trigger or a gateway in front of PostgREST; otherwise use an RPC function that
+// calls jsonb_set.
+func JSONBSet(column, path string, value interface{}) map[string]interface{} {
+	b, err := json.Marshal(value)
+	if err != nil {
+		b = []byte("null")
+	}
+	keys := strings.Split(path, ".")
+	return map[string]interface{}{
+		column: fmt.Sprintf("jsonb_set(%s, %s, %s)", column, sqlQuote("{"+strings.Join(keys, ",")+"}"), sqlQuote(string(b))),
+	}
+}
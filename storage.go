@@ -0,0 +1,573 @@
+package supabasego
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StorageClient provides access to the Supabase Storage API.
+// Bucket management calls authenticate with the client's API key.
+type StorageClient struct {
+	client *Client
+}
+
+// StorageClientInterface is the set of StorageClient methods, so code that uses Storage can be
+// tested against a MockStorageClient.
+type StorageClientInterface interface {
+	CreateBucket(name string, opts BucketOptions) error
+	GetBucket(name string) (*Bucket, error)
+	GetOrCreateBucket(name string, opts BucketOptions) (*Bucket, bool, error)
+	UpdateBucket(name string, opts BucketOptions) error
+	EmptyBucket(name string, jwtToken string) error
+	CopyAcrossBuckets(sourceBucket, sourcePath, destBucket, destPath, jwtToken string) error
+	Bucket(name string) *BucketClient
+}
+
+var _ StorageClientInterface = (*StorageClient)(nil)
+
+// Storage returns a StorageClient for the Supabase Storage API.
+func (c *Client) Storage() *StorageClient {
+	return &StorageClient{client: c}
+}
+
+// token returns jwtToken, or the client's API key when jwtToken is empty.
+func (s *StorageClient) token(jwtToken string) string {
+	if jwtToken != "" {
+		return jwtToken
+	}
+	return s.client.APIKey
+}
+
+// Bucket is a Supabase Storage bucket.
+type Bucket struct {
+	ID               string    `json:"id"`
+	Name             string    `json:"name"`
+	Owner            string    `json:"owner,omitempty"`
+	Public           bool      `json:"public"`
+	FileSizeLimit    *int64    `json:"file_size_limit,omitempty"` // Bytes; nil means no limit
+	AllowedMimeTypes []string  `json:"allowed_mime_types,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// BucketOptions configures a bucket when creating or updating it.
+type BucketOptions struct {
+	Public           bool
+	FileSizeLimit    int64    // Bytes; zero means no limit
+	AllowedMimeTypes []string // Empty allows all types
+}
+
+type bucketRequest struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	Public           bool     `json:"public"`
+	FileSizeLimit    *int64   `json:"file_size_limit"`
+	AllowedMimeTypes []string `json:"allowed_mime_types"`
+}
+
+func newBucketRequest(name string, opts BucketOptions) (bucketRequest, error) {
+	if opts.FileSizeLimit < 0 {
+		return bucketRequest{}, fmt.Errorf("supabase: file size limit must be non-negative, got %d", opts.FileSizeLimit)
+	}
+	body := bucketRequest{
+		ID:               name,
+		Name:             name,
+		Public:           opts.Public,
+		AllowedMimeTypes: opts.AllowedMimeTypes,
+	}
+	if opts.FileSizeLimit > 0 {
+		body.FileSizeLimit = &opts.FileSizeLimit
+	}
+	return body, nil
+}
+
+// CreateBucket creates a bucket. Returns ErrConflict if it already exists.
+func (s *StorageClient) CreateBucket(name string, opts BucketOptions) error {
+	body, err := newBucketRequest(name, opts)
+	if err != nil {
+		return err
+	}
+	req, err := s.client.newRequest("POST", STORAGE_URL+"/bucket", body, s.client.APIKey)
+	if err != nil {
+		return err
+	}
+	return s.client.doJSON(req, "create bucket", nil)
+}
+
+// GetBucket fetches the configuration of a single bucket. Returns ErrNotFound if it does not exist.
+func (s *StorageClient) GetBucket(name string) (*Bucket, error) {
+	req, err := s.client.newRequest("GET", STORAGE_URL+"/bucket/"+url.PathEscape(name), nil, s.client.APIKey)
+	if err != nil {
+		return nil, err
+	}
+	var bucket Bucket
+	if err := s.client.doJSON(req, "get bucket", &bucket); err != nil {
+		return nil, err
+	}
+	return &bucket, nil
+}
+
+// GetOrCreateBucket returns the named bucket, creating it with opts if it does not exist. The
+// second result reports whether it was created. Existing buckets are returned as they are, even
+// if their settings differ from opts. If another process creates the bucket concurrently, that
+// bucket is returned with created false.
+func (s *StorageClient) GetOrCreateBucket(name string, opts BucketOptions) (*Bucket, bool, error) {
+	bucket, err := s.GetBucket(name)
+	if err == nil {
+		return bucket, false, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, false, err
+	}
+	if err := s.CreateBucket(name, opts); err != nil {
+		// Lost a race with a concurrent create: the bucket exists now.
+		if bucket, getErr := s.GetBucket(name); getErr == nil {
+			return bucket, false, nil
+		}
+		return nil, false, err
+	}
+	bucket, err = s.GetBucket(name)
+	if err != nil {
+		return nil, true, err
+	}
+	return bucket, true, nil
+}
+
+// UpdateBucket changes the visibility, file size limit and allowed MIME types of an existing bucket.
+func (s *StorageClient) UpdateBucket(name string, opts BucketOptions) error {
+	body, err := newBucketRequest(name, opts)
+	if err != nil {
+		return err
+	}
+	req, err := s.client.newRequest("PUT", STORAGE_URL+"/bucket/"+url.PathEscape(name), body, s.client.APIKey)
+	if err != nil {
+		return err
+	}
+	return s.client.doJSON(req, "update bucket", nil)
+}
+
+// EmptyBucket deletes every object in a bucket but keeps the bucket itself.
+// An empty jwtToken authenticates with the client's API key. Returns ErrNotFound if the bucket does not exist.
+func (s *StorageClient) EmptyBucket(name string, jwtToken string) error {
+	req, err := s.client.newRequest("POST", STORAGE_URL+"/bucket/"+url.PathEscape(name)+"/empty", nil, s.token(jwtToken))
+	if err != nil {
+		return err
+	}
+	return s.client.doJSON(req, "empty bucket", nil)
+}
+
+// CopyAcrossBuckets copies an object to another bucket (or another path in the same bucket),
+// e.g. from a temp bucket to a permanent one after processing. The source is kept; delete it
+// afterwards to move the object. An empty jwtToken authenticates with the client's API key.
+func (s *StorageClient) CopyAcrossBuckets(sourceBucket, sourcePath, destBucket, destPath, jwtToken string) error {
+	body := map[string]string{
+		"bucketId":          sourceBucket,
+		"sourceKey":         sourcePath,
+		"destinationBucket": destBucket,
+		"destinationKey":    destPath,
+	}
+	req, err := s.client.newRequest("POST", STORAGE_URL+"/object/copy", body, s.token(jwtToken))
+	if err != nil {
+		return err
+	}
+	return s.client.doJSON(req, "copy object", nil)
+}
+
+// BucketClient provides object operations scoped to a single bucket, the equivalent of
+// supabase.storage.from(bucket) in the JavaScript SDK. Get one with StorageClient.Bucket.
+type BucketClient struct {
+	storage *StorageClient
+	name    string
+}
+
+// Bucket returns a BucketClient for the named bucket.
+func (s *StorageClient) Bucket(name string) *BucketClient {
+	return &BucketClient{storage: s, name: name}
+}
+
+// Name returns the name of the bucket.
+func (b *BucketClient) Name() string {
+	return b.name
+}
+
+// objectURL returns the API path for an object in the bucket, e.g. /storage/v1/object/<bucket>/<path>.
+func (b *BucketClient) objectURL(path string) string {
+	return STORAGE_URL + "/object/" + url.PathEscape(b.name) + "/" + escapeObjectPath(path)
+}
+
+// escapeObjectPath escapes each segment of a slash-separated object path.
+func escapeObjectPath(path string) string {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return strings.Join(segments, "/")
+}
+
+// FileMetadata describes a stored object, as reported by its response headers.
+type FileMetadata struct {
+	ContentType   string
+	ContentLength int64
+	ETag          string
+	LastModified  time.Time
+	CacheControl  string
+}
+
+// GetMetadata fetches an object's metadata with a HEAD request, without downloading it.
+// Returns ErrNotFound if the object does not exist.
+func (b *BucketClient) GetMetadata(path, jwtToken string) (*FileMetadata, error) {
+	req, err := b.storage.client.newRequest("HEAD", b.objectURL(path), nil, b.storage.token(jwtToken))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.storage.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get metadata request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, responseError("get metadata", resp)
+	}
+	meta := &FileMetadata{
+		ContentType:  resp.Header.Get("Content-Type"),
+		ETag:         resp.Header.Get("ETag"),
+		CacheControl: resp.Header.Get("Cache-Control"),
+	}
+	if n, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil {
+		meta.ContentLength = n
+	}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		meta.LastModified = t
+	}
+	return meta, nil
+}
+
+// ObjectInfo describes a stored object. See BucketClient.ObjectInfo.
+type ObjectInfo struct {
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+	CacheControl string
+}
+
+// ObjectInfo fetches an object's size, type and caching headers with a HEAD request, without
+// downloading it. Returns ErrNotFound if the object does not exist and ErrForbidden if the
+// caller may not read it.
+func (b *BucketClient) ObjectInfo(path, jwtToken string) (*ObjectInfo, error) {
+	meta, err := b.GetMetadata(path, jwtToken)
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectInfo{
+		Size:         meta.ContentLength,
+		ContentType:  meta.ContentType,
+		ETag:         meta.ETag,
+		LastModified: meta.LastModified,
+		CacheControl: meta.CacheControl,
+	}, nil
+}
+
+// FileObject is an entry returned by List: a stored object, or a folder when ID is empty.
+type FileObject struct {
+	Name           string                 `json:"name"`
+	ID             string                 `json:"id"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+	CreatedAt      time.Time              `json:"created_at"`
+	LastAccessedAt time.Time              `json:"last_accessed_at"`
+	Metadata       map[string]interface{} `json:"metadata"` // e.g. size, mimetype, eTag
+}
+
+// isFolder reports whether the entry is a virtual folder rather than an object.
+func (f FileObject) isFolder() bool {
+	return f.ID == "" || strings.HasSuffix(f.Name, "/")
+}
+
+// ListOptions controls a List call.
+type ListOptions struct {
+	Limit  int    // Defaults to 100
+	Offset int    // Number of entries to skip, for paging
+	Search string // Only return entries whose name contains this string
+}
+
+// storageListLimit is the default page size for List.
+const storageListLimit = 100
+
+// List returns the objects and folders directly under prefix (a folder path, "" for the bucket
+// root), sorted by name. Entries in sub-folders are not included; see ListRecursive.
+func (b *BucketClient) List(prefix string, opts ListOptions, jwtToken string) ([]FileObject, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = storageListLimit
+	}
+	body := map[string]interface{}{
+		"prefix": strings.Trim(prefix, "/"),
+		"limit":  opts.Limit,
+		"offset": opts.Offset,
+		"search": opts.Search,
+		"sortBy": map[string]string{"column": "name", "order": "asc"},
+	}
+	req, err := b.storage.client.newRequest("POST", STORAGE_URL+"/object/list/"+url.PathEscape(b.name), body, b.storage.token(jwtToken))
+	if err != nil {
+		return nil, err
+	}
+	var objects []FileObject
+	if err := b.storage.client.doJSON(req, "list objects", &objects); err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// ListRecursive returns every object under prefix, including those in sub-folders, paging
+// through List and descending into folders depth-first. Names in the result are paths relative
+// to prefix, e.g. "2024/05/report.pdf"; folders themselves are not included.
+func (b *BucketClient) ListRecursive(prefix, jwtToken string) ([]FileObject, error) {
+	var files []FileObject
+	var walk func(rel string) error
+	walk = func(rel string) error {
+		dir := strings.Trim(prefix, "/")
+		if rel != "" {
+			dir = strings.TrimPrefix(dir+"/"+rel, "/")
+		}
+		for offset := 0; ; offset += storageListLimit {
+			page, err := b.List(dir, ListOptions{Offset: offset}, jwtToken)
+			if err != nil {
+				return err
+			}
+			for _, obj := range page {
+				name := strings.TrimSuffix(obj.Name, "/")
+				if rel != "" {
+					name = rel + "/" + name
+				}
+				if obj.isFolder() {
+					if err := walk(name); err != nil {
+						return err
+					}
+					continue
+				}
+				obj.Name = name
+				files = append(files, obj)
+			}
+			if len(page) < storageListLimit {
+				return nil
+			}
+		}
+	}
+	if err := walk(""); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// SignedURLResult is one entry of a BatchCreateSignedURLs response. Error is set instead of
+// SignedURL when that path could not be signed (e.g. it does not exist).
+type SignedURLResult struct {
+	Path      string `json:"path"`
+	SignedURL string `json:"signedURL"`
+	Error     string `json:"error"`
+}
+
+// BatchCreateSignedURLs creates signed download URLs for many objects in one request.
+// expiresIn is the validity in seconds. Returned URLs are absolute.
+func (b *BucketClient) BatchCreateSignedURLs(paths []string, expiresIn int, jwtToken string) ([]SignedURLResult, error) {
+	body := map[string]interface{}{"paths": paths, "expiresIn": expiresIn}
+	req, err := b.storage.client.newRequest("POST", STORAGE_URL+"/object/sign/"+url.PathEscape(b.name), body, b.storage.token(jwtToken))
+	if err != nil {
+		return nil, err
+	}
+	var results []SignedURLResult
+	if err := b.storage.client.doJSON(req, "create signed urls", &results); err != nil {
+		return nil, err
+	}
+	for i := range results {
+		if results[i].SignedURL != "" {
+			results[i].SignedURL = b.storage.client.BaseURL + STORAGE_URL + results[i].SignedURL
+		}
+	}
+	return results, nil
+}
+
+// UploadURL is a pre-signed URL that lets a client upload one object without credentials.
+type UploadURL struct {
+	SignedURL string // Absolute URL to PUT the file to
+	Token     string // The upload token embedded in SignedURL
+	Path      string // Object path within the bucket
+}
+
+// CreateUploadURL creates a pre-signed URL for uploading an object directly from a client
+// (e.g. a browser), so file data does not pass through your server. expiresIn is the validity
+// in seconds; servers that do not support a custom expiry use their default of two hours.
+//
+// The client uploads with a PUT of the file to SignedURL, setting Content-Type to the file's
+// type:
+//
+//	PUT <SignedURL>
+//	Content-Type: image/png
+//
+//	<file bytes>
+//
+// No Authorization header is needed; the token in the URL authorizes the upload.
+func (b *BucketClient) CreateUploadURL(path string, expiresIn int, jwtToken string) (*UploadURL, error) {
+	signed, err := b.signUpload(path, map[string]int{"expiresIn": expiresIn}, false, jwtToken)
+	if err != nil {
+		return nil, err
+	}
+	return &UploadURL{SignedURL: signed.String(), Token: signed.Query().Get("token"), Path: path}, nil
+}
+
+// SignedUploadURL is returned by GetSignedUploadURL.
+type SignedUploadURL struct {
+	URL   string // Absolute URL to PUT the file to, including the token
+	Token string // The upload token, also present in URL as the token query parameter
+}
+
+// GetSignedUploadURL creates a signed URL that lets a client upload one object to path without
+// credentials. With upsert, the upload replaces an existing object instead of failing. The URL
+// is valid for two hours.
+//
+// Unlike a signed download URL, which is fetched with GET, the client must PUT the file to URL:
+//
+//	PUT <URL>
+//	Content-Type: image/png
+//
+//	<file bytes>
+//
+// No Authorization header is needed; the token query parameter authorizes the upload, so keep
+// it in the URL.
+func (b *BucketClient) GetSignedUploadURL(path string, upsert bool, jwtToken string) (*SignedUploadURL, error) {
+	signed, err := b.signUpload(path, nil, upsert, jwtToken)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedUploadURL{URL: signed.String(), Token: signed.Query().Get("token")}, nil
+}
+
+// signUpload requests a signed upload URL for path and returns it as an absolute URL.
+func (b *BucketClient) signUpload(path string, body interface{}, upsert bool, jwtToken string) (*url.URL, error) {
+	req, err := b.storage.client.newRequest("POST", STORAGE_URL+"/object/upload/sign/"+url.PathEscape(b.name)+"/"+escapeObjectPath(path), body, b.storage.token(jwtToken))
+	if err != nil {
+		return nil, err
+	}
+	if upsert {
+		req.Header.Set("x-upsert", "true")
+	}
+	var res struct {
+		URL string `json:"url"`
+	}
+	if err := b.storage.client.doJSON(req, "create upload url", &res); err != nil {
+		return nil, err
+	}
+	signed, err := url.Parse(b.storage.client.BaseURL + STORAGE_URL + res.URL)
+	if err != nil {
+		return nil, fmt.Errorf("supabase: invalid upload url %q: %w", res.URL, err)
+	}
+	return signed, nil
+}
+
+// Upload stores content at path in the bucket. Uploading to an existing path fails with ErrConflict.
+func (b *BucketClient) Upload(path string, content io.Reader, contentType string, jwtToken string) error {
+	return b.upload(path, content, -1, contentType, jwtToken)
+}
+
+// UploadWithProgress is like Upload for content of a known size, calling progress after each
+// chunk is read with the bytes uploaded so far and the total.
+func (b *BucketClient) UploadWithProgress(path string, content io.Reader, size int64, contentType string, jwtToken string, progress func(uploaded, total int64)) error {
+	return b.upload(path, NewProgressReader(content, size, progress), size, contentType, jwtToken)
+}
+
+func (b *BucketClient) upload(path string, content io.Reader, size int64, contentType string, jwtToken string) error {
+	req, err := b.storage.client.newRawRequest("POST", b.objectURL(path), content, contentType, b.storage.token(jwtToken))
+	if err != nil {
+		return err
+	}
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	return b.storage.client.doJSON(req, "upload", nil)
+}
+
+// UploadFromURL downloads sourceURL and streams it into the bucket at destPath, keeping the
+// source's Content-Type. It is meant for migrating assets, e.g. from a CDN. The download fails
+// if it is larger than maxBytes (which guards against redirects to huge files); maxBytes <= 0
+// means no limit.
+func (b *BucketClient) UploadFromURL(destPath, sourceURL string, maxBytes int64, jwtToken string) error {
+	resp, err := b.storage.client.HTTPClient.Get(sourceURL)
+	if err != nil {
+		return fmt.Errorf("download %s failed: %w", sourceURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("supabase: download %s failed: %s", sourceURL, resp.Status)
+	}
+	if maxBytes > 0 && resp.ContentLength > maxBytes {
+		return fmt.Errorf("supabase: %s is %d bytes, over the %d byte limit", sourceURL, resp.ContentLength, maxBytes)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	var body io.Reader = resp.Body
+	if maxBytes > 0 {
+		body = &maxBytesReader{r: resp.Body, remaining: maxBytes, source: sourceURL}
+	}
+	return b.upload(destPath, body, resp.ContentLength, contentType, jwtToken)
+}
+
+// maxBytesReader fails once more than the allowed number of bytes has been read.
+type maxBytesReader struct {
+	r         io.Reader
+	remaining int64
+	source    string
+}
+
+func (m *maxBytesReader) Read(p []byte) (int, error) {
+	if m.remaining < 0 {
+		return 0, fmt.Errorf("supabase: %s exceeds the size limit", m.source)
+	}
+	if int64(len(p)) > m.remaining+1 {
+		p = p[:m.remaining+1]
+	}
+	n, err := m.r.Read(p)
+	m.remaining -= int64(n)
+	if m.remaining < 0 {
+		return n, fmt.Errorf("supabase: %s exceeds the size limit", m.source)
+	}
+	return n, err
+}
+
+// ProgressReader wraps an io.Reader and reports how many bytes have been read. It can be used
+// on its own to track any transfer.
+type ProgressReader struct {
+	reader     io.Reader
+	total      int64
+	read       int64
+	onProgress func(read, total int64)
+}
+
+// NewProgressReader returns a ProgressReader that calls onProgress (if non-nil) after every
+// Read with the running byte count and total (pass -1 if unknown).
+func NewProgressReader(r io.Reader, total int64, onProgress func(read, total int64)) *ProgressReader {
+	return &ProgressReader{reader: r, total: total, onProgress: onProgress}
+}
+
+// Read implements io.Reader.
+func (p *ProgressReader) Read(buf []byte) (int, error) {
+	n, err := p.reader.Read(buf)
+	if n > 0 {
+		p.read += int64(n)
+		if p.onProgress != nil {
+			p.onProgress(p.read, p.total)
+		}
+	}
+	return n, err
+}
+
+// BytesRead returns the number of bytes read so far.
+func (p *ProgressReader) BytesRead() int64 {
+	return p.read
+}
@@ -0,0 +1,134 @@
+package supabasego
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// MockStorageClient is a StorageClientInterface for unit tests. Each Storage API call must match
+// an expectation registered with Expect, which supplies the response:
+//
+//	mock := supabasego.NewMockStorageClient()
+//	mock.Expect("GET", "/bucket/avatars").Return([]byte(`{"id":"avatars","name":"avatars"}`), nil)
+//	bucket, err := mock.GetBucket("avatars")
+//	// ...
+//	if err := mock.Verify(); err != nil {
+//		t.Fatal(err)
+//	}
+//
+// Bucket returns a BucketClient whose calls are served by the same expectations.
+type MockStorageClient struct {
+	*StorageClient
+
+	mu           sync.Mutex
+	expectations []*MockExpectation
+}
+
+var _ StorageClientInterface = (*MockStorageClient)(nil)
+
+// MockExpectation is one expected call registered with MockStorageClient.Expect.
+type MockExpectation struct {
+	method string
+	path   string
+	body   []byte
+	err    error
+	met    bool
+}
+
+// NewMockStorageClient returns a MockStorageClient with no expectations.
+func NewMockStorageClient() *MockStorageClient {
+	m := &MockStorageClient{}
+	client := NewClient(Config{
+		BaseURL:    "http://supabase.mock",
+		APIKey:     "mock-api-key",
+		HTTPClient: &http.Client{Transport: mockStorageTransport{m}},
+	})
+	m.StorageClient = client.Storage()
+	return m
+}
+
+// Expect registers an expected call. path is relative to the Storage API root and unescaped,
+// e.g. "/bucket/avatars" or "/object/avatars/folder/a.png"; query strings are ignored. Each
+// expectation matches one call, in the order registered.
+func (m *MockStorageClient) Expect(method, path string) *MockExpectation {
+	e := &MockExpectation{method: strings.ToUpper(method), path: path}
+	m.mu.Lock()
+	m.expectations = append(m.expectations, e)
+	m.mu.Unlock()
+	return e
+}
+
+// Return sets the response body for the expected call. A non-nil err fails the call instead: an
+// *APIError is served as an HTTP response with its status code and body (so errors.Is against
+// ErrNotFound and friends behaves as with a real server), and any other error is returned as a
+// transport error.
+func (e *MockExpectation) Return(body []byte, err error) *MockExpectation {
+	e.body, e.err = body, err
+	return e
+}
+
+// Verify returns an error listing every expectation that was not called.
+func (m *MockStorageClient) Verify() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var unmet []string
+	for _, e := range m.expectations {
+		if !e.met {
+			unmet = append(unmet, e.method+" "+e.path)
+		}
+	}
+	if len(unmet) > 0 {
+		return fmt.Errorf("supabase: unmet storage expectations: %s", strings.Join(unmet, ", "))
+	}
+	return nil
+}
+
+// match consumes the first unmet expectation for method and path.
+func (m *MockStorageClient) match(method, path string) (*MockExpectation, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.expectations {
+		if !e.met && e.method == method && e.path == path {
+			e.met = true
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// mockStorageTransport serves a MockStorageClient's requests from its expectations.
+type mockStorageTransport struct {
+	mock *MockStorageClient
+}
+
+func (t mockStorageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	path := strings.TrimPrefix(req.URL.Path, STORAGE_URL)
+	e, ok := t.mock.match(req.Method, path)
+	if !ok {
+		return nil, fmt.Errorf("supabase: unexpected storage request %s %s", req.Method, path)
+	}
+	status, body := http.StatusOK, e.body
+	if e.err != nil {
+		var apiErr *APIError
+		if !errors.As(e.err, &apiErr) {
+			return nil, e.err
+		}
+		status, body = apiErr.StatusCode, []byte(apiErr.Body)
+	}
+	return &http.Response{
+		StatusCode:    status,
+		Status:        http.StatusText(status),
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,228 @@
+package supabasego
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// resumableChunkSize is the default UploadLarge chunk size. Supabase Storage expects resumable
+// uploads to be sent in chunks of exactly 6 MB, apart from the last one.
+const resumableChunkSize = 6 << 20
+
+// tusVersion is the TUS protocol version spoken by the Storage resumable upload endpoint.
+const tusVersion = "1.0.0"
+
+// PartialUploadTracker records the progress of an UploadLarge upload so that an interrupted
+// upload can be resumed instead of restarted. It can be saved as JSON (e.g. after each chunk,
+// from OnChunkDone) and loaded again before calling UploadLargeWithTracker.
+type PartialUploadTracker struct {
+	Path      string `json:"path"`       // Object path within the bucket
+	UploadURL string `json:"upload_url"` // Resumable upload URL; empty until the upload is created
+	ChunkSize int64  `json:"chunk_size"`
+	Completed []int  `json:"completed"` // Indexes of the chunks uploaded so far, in order
+
+	// OnChunkDone, if set, is called after each chunk is uploaded.
+	OnChunkDone func(t *PartialUploadTracker, chunk int) `json:"-"`
+}
+
+// ChunkDone reports whether the given chunk has been uploaded.
+func (t *PartialUploadTracker) ChunkDone(chunk int) bool {
+	for _, c := range t.Completed {
+		if c == chunk {
+			return true
+		}
+	}
+	return false
+}
+
+// UploadLarge uploads content too large for a single request, such as video, using the
+// Storage resumable (TUS) upload protocol. r is read and sent chunkSize bytes at a time, so the
+// file is never held in memory whole; chunkSize <= 0 uses 6 MB, the size Supabase expects.
+// Uploading to an existing path fails with ErrConflict. To be able to resume after a failure,
+// use UploadLargeWithTracker.
+func (b *BucketClient) UploadLarge(path string, r io.Reader, contentType string, chunkSize int64, jwtToken string) error {
+	return b.UploadLargeWithTracker(path, r, contentType, chunkSize, &PartialUploadTracker{}, jwtToken)
+}
+
+// UploadLargeWithTracker is UploadLarge recording its progress in tracker. If tracker holds an
+// unfinished upload of path (e.g. loaded from a previous run that failed), the upload resumes:
+// r must supply the same content from the start, and the chunks the server already has are
+// skipped rather than sent again. An upload the server no longer knows about, e.g. because it
+// expired, is started over.
+func (b *BucketClient) UploadLargeWithTracker(path string, r io.Reader, contentType string, chunkSize int64, tracker *PartialUploadTracker, jwtToken string) error {
+	if chunkSize <= 0 {
+		chunkSize = resumableChunkSize
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	token := b.storage.token(jwtToken)
+
+	var offset int64
+	if tracker.UploadURL != "" {
+		if tracker.Path != path || tracker.ChunkSize != chunkSize {
+			return fmt.Errorf("supabase: upload tracker is for %s in chunks of %d bytes, not %s in chunks of %d", tracker.Path, tracker.ChunkSize, path, chunkSize)
+		}
+		off, length, err := b.tusOffset(tracker.UploadURL, token)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			tracker.UploadURL = ""
+		case err != nil:
+			return err
+		case length >= 0 && off == length:
+			return nil // Already complete
+		default:
+			// The server's offset is authoritative; it may be behind the tracker if a chunk was
+			// lost, or ahead if the tracker was not saved after the last chunk.
+			offset = off
+			tracker.Completed = tracker.Completed[:0]
+			for c := 0; c < int(off/chunkSize); c++ {
+				tracker.Completed = append(tracker.Completed, c)
+			}
+		}
+	}
+	if tracker.UploadURL == "" {
+		u, err := b.tusCreate(path, contentType, token)
+		if err != nil {
+			return err
+		}
+		*tracker = PartialUploadTracker{Path: path, UploadURL: u, ChunkSize: chunkSize, OnChunkDone: tracker.OnChunkDone}
+	}
+
+	if offset > 0 {
+		if n, err := io.CopyN(io.Discard, r, offset); err != nil {
+			return fmt.Errorf("supabase: skipping %d uploaded bytes failed after %d: %w", offset, n, err)
+		}
+	}
+	br := bufio.NewReader(r)
+	buf := make([]byte, chunkSize)
+	for chunk := int(offset / chunkSize); ; chunk++ {
+		// The first chunk of a resumed upload may be short so later chunks stay aligned.
+		n, err := io.ReadFull(br, buf[:chunkSize-offset%chunkSize])
+		last := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !last {
+			return fmt.Errorf("supabase: reading upload content failed: %w", err)
+		}
+		if !last {
+			_, err := br.Peek(1)
+			if err != nil && err != io.EOF {
+				return fmt.Errorf("supabase: reading upload content failed: %w", err)
+			}
+			last = err == io.EOF
+		}
+		length := int64(-1)
+		if last {
+			length = offset + int64(n)
+		}
+		offset, err = b.tusPatch(tracker.UploadURL, offset, buf[:n], length, token)
+		if err != nil {
+			return fmt.Errorf("upload chunk %d: %w", chunk, err)
+		}
+		tracker.Completed = append(tracker.Completed, chunk)
+		if tracker.OnChunkDone != nil {
+			tracker.OnChunkDone(tracker, chunk)
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// tusRequest creates a request to the resumable upload endpoint at the absolute URL u.
+func (b *BucketClient) tusRequest(method, u string, body io.Reader, token string) (*http.Request, error) {
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("apikey", b.storage.client.APIKey)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Tus-Resumable", tusVersion)
+	return req, nil
+}
+
+// tusCreate creates a resumable upload of unknown length and returns its URL.
+func (b *BucketClient) tusCreate(path, contentType, token string) (string, error) {
+	req, err := b.tusRequest("POST", b.storage.client.BaseURL+STORAGE_URL+"/upload/resumable", nil, token)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.StdEncoding.EncodeToString
+	req.Header.Set("Upload-Defer-Length", "1")
+	req.Header.Set("Upload-Metadata", "bucketName "+enc([]byte(b.name))+",objectName "+enc([]byte(path))+",contentType "+enc([]byte(contentType)))
+	req.Header.Set("x-upsert", "false")
+	resp, err := b.storage.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("create upload request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return "", responseError("create upload", resp)
+	}
+	loc, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.Header.Get("Location") == "" {
+		return "", fmt.Errorf("supabase: create upload returned no valid Location")
+	}
+	return loc.String(), nil
+}
+
+// tusOffset returns how many bytes of an upload the server has, and its total length or -1 if
+// that has not been declared yet.
+func (b *BucketClient) tusOffset(u, token string) (offset, length int64, err error) {
+	req, err := b.tusRequest("HEAD", u, nil, token)
+	if err != nil {
+		return 0, 0, err
+	}
+	resp, err := b.storage.client.Do(req)
+	if err != nil {
+		return 0, 0, fmt.Errorf("get upload offset request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusGone {
+		return 0, 0, fmt.Errorf("%w: upload expired", ErrNotFound)
+	}
+	if resp.StatusCode >= 400 {
+		return 0, 0, responseError("get upload offset", resp)
+	}
+	offset, err = strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("supabase: invalid Upload-Offset %q", resp.Header.Get("Upload-Offset"))
+	}
+	length = -1
+	if l, err := strconv.ParseInt(resp.Header.Get("Upload-Length"), 10, 64); err == nil {
+		length = l
+	}
+	return offset, length, nil
+}
+
+// tusPatch sends data at offset and returns the new offset. length, if not negative, declares
+// the total size of the upload, which completes it.
+func (b *BucketClient) tusPatch(u string, offset int64, data []byte, length int64, token string) (int64, error) {
+	req, err := b.tusRequest("PATCH", u, bytes.NewReader(data), token)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+	req.Header.Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	if length >= 0 {
+		req.Header.Set("Upload-Length", strconv.FormatInt(length, 10))
+	}
+	resp, err := b.storage.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return 0, responseError("upload chunk", resp)
+	}
+	next, err := strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || next != offset+int64(len(data)) {
+		return 0, fmt.Errorf("supabase: server reported Upload-Offset %q after chunk, want %d", resp.Header.Get("Upload-Offset"), offset+int64(len(data)))
+	}
+	return next, nil
+}
@@ -1,7 +1,25 @@
 package supabasego
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -53,7 +71,7 @@ func TestTableCRUD(t *testing.T) {
 	}
 	// --- Update ---
 	update := map[string]interface{}{"plan": "pro"}
-	err = table.Eq("user_id", userID).Update(update, "")
+	err = table.Eq("user_id", userID).Update(update, nil, "")
 	if err != nil {
 		t.Fatalf("Update failed: %v", err)
 	}
@@ -86,3 +104,2659 @@ func TestTableCRUD_Scaffold(t *testing.T) {
 	// Scaffold test for Table CRUD methods.
 	// Real tests to be added as implementation progresses.
 }
+
+func TestQueryBuilderParams(t *testing.T) {
+	qb := NewClient(Config{}).From("tenants").
+		Where(Eq("plan", "pro"), Gte("max_users", 5)).
+		OrderBy("created_at", "DESC").
+		Limit(10).
+		Offset(20).
+		Columns("id", "name")
+
+	params := qb.Params()
+	want := map[string]string{
+		"plan":      "eq.pro",
+		"max_users": "gte.5",
+		"order":     "created_at.desc",
+		"limit":     "10",
+		"offset":    "20",
+		"select":    "id,name",
+	}
+	for k, v := range want {
+		if got := params.Get(k); got != v {
+			t.Errorf("param %s = %q, want %q", k, got, v)
+		}
+	}
+
+	// OrderBy builds the same order clause as Table.OrderBy.
+	client := NewClient(Config{})
+	for _, dir := range []string{"DESC", "asc", "sideways"} {
+		got := client.From("tenants").OrderBy("created_at", dir).Params().Get("order")
+		want := client.Table("tenants").OrderBy("created_at", dir).selectParams().Get("order")
+		if got != want {
+			t.Errorf("OrderBy(%q) = %q, Table.OrderBy gives %q", dir, got, want)
+		}
+	}
+}
+
+func TestFilterGroupBuild(t *testing.T) {
+	f := NewFilterGroup().
+		Or(Eq("plan", "pro"), Gt("max_users", 5)).
+		Not(Like("name", "test%")).
+		Build()
+
+	want := "and(or(plan.eq.pro,max_users.gt.5),name.not.like.test%)"
+	if got := f.toQuery(); got != want {
+		t.Errorf("toQuery() = %q, want %q", got, want)
+	}
+}
+
+func TestGroupFilterParams(t *testing.T) {
+	table := NewClient(Config{}).Table("tenants").
+		Or(Eq("plan", "pro"), And(Gt("max_users", 5), Lt("max_users", 10))).
+		Not(Or(Eq("plan", "free"), Eq("name", "x")))
+	params := table.filterParams()
+	if got, want := params.Get("or"), "(plan.eq.pro,and(max_users.gt.5,max_users.lt.10))"; got != want {
+		t.Errorf("or param = %q, want %q", got, want)
+	}
+	if got, want := params.Get("not.or"), "(plan.eq.free,name.eq.x)"; got != want {
+		t.Errorf("not.or param = %q, want %q", got, want)
+	}
+}
+
+func TestCRUDNilFilterValues(t *testing.T) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+	client := NewClient(Config{BaseURL: srv.URL})
+	filtered := func() *Table {
+		var deletedAt *time.Time
+		var owner *string
+		return client.Table("tenants").Eq("deleted_at", deletedAt).NotEq("owner", owner)
+	}
+
+	var rows []TestTenant
+	ops := map[string]func(*Table) error{
+		"Select": func(t *Table) error { return t.Select(&rows, "") },
+		"Insert": func(t *Table) error { return t.Insert(&[]TestTenant{{Name: "x"}}, "") },
+		"Update": func(t *Table) error { return t.Update(map[string]interface{}{"plan": "pro"}, nil, "") },
+		"Delete": func(t *Table) error { return t.Delete("") },
+	}
+	for name, op := range ops {
+		queries = nil
+		if err := op(filtered()); err != nil {
+			t.Fatalf("%s failed: %v", name, err)
+		}
+		q, _ := url.ParseQuery(queries[0])
+		if strings.Contains(queries[0], "nil") || strings.Contains(queries[0], "0x") {
+			t.Errorf("%s sent query %q", name, queries[0])
+		}
+		if name == "Insert" {
+			continue
+		}
+		if got := q.Get("deleted_at"); got != "is.null" {
+			t.Errorf("%s deleted_at = %q, want is.null", name, got)
+		}
+		if got := q.Get("owner"); got != "not.is.null" {
+			t.Errorf("%s owner = %q, want not.is.null", name, got)
+		}
+
+		// Other operators cannot compare against NULL. Reads leave the filter out, but updates
+		// and deletes must fail rather than change every row the other filters match.
+		queries = nil
+		err := op(filtered().Gt("archived_at", nil))
+		switch name {
+		case "Update", "Delete":
+			if err == nil || len(queries) != 0 {
+				t.Errorf("%s with Gt(nil) = %v after %d requests, want an error and no request", name, err, len(queries))
+			}
+			queries = nil
+			if err := op(filtered().Or(Eq("plan", "pro"), Not(Lt("archived_at", nil)))); err == nil || len(queries) != 0 {
+				t.Errorf("%s with nested Lt(nil) = %v after %d requests, want an error and no request", name, err, len(queries))
+			}
+		case "Select":
+			if q, _ := url.ParseQuery(queries[0]); err != nil || q.Has("archived_at") {
+				t.Errorf("Select with Gt(nil) = %v, query %q", err, queries[0])
+			}
+		}
+	}
+}
+
+func TestInsertMaps(t *testing.T) {
+	var prefer []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefer = append(prefer, r.Header.Get("Prefer"))
+		var rows []map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&rows); err != nil {
+			t.Errorf("body is not a JSON array: %v", err)
+		}
+		for i, row := range rows {
+			row["id"] = float64(i + 1)
+		}
+		json.NewEncoder(w).Encode(rows)
+	}))
+	defer srv.Close()
+	client := NewClient(Config{BaseURL: srv.URL})
+
+	records := []map[string]interface{}{{"name": "a"}}
+	if err := client.Table("imports").InsertMaps(records, ""); err != nil {
+		t.Fatalf("InsertMaps failed: %v", err)
+	}
+	if records[0]["id"] != float64(1) {
+		t.Errorf("record not updated with generated id: %v", records[0])
+	}
+
+	records = []map[string]interface{}{{"name": "b"}, {"name": "c"}}
+	if err := client.Table("imports").ReturnMinimal().InsertMaps(records, ""); err != nil {
+		t.Fatalf("InsertMaps with ReturnMinimal failed: %v", err)
+	}
+	if _, ok := records[0]["id"]; ok {
+		t.Errorf("ReturnMinimal record was modified: %v", records[0])
+	}
+	if want := []string{"return=representation", "return=minimal"}; len(prefer) != 2 || prefer[0] != want[0] || prefer[1] != want[1] {
+		t.Errorf("Prefer headers = %q, want %q", prefer, want)
+	}
+}
+
+func TestTableWithDefaultsReset(t *testing.T) {
+	table := NewClient(Config{}).TableWithDefaults("projects", Eq("tenant_id", "t1"))
+	clone := table.Eq("name", "alpha").Limit(5).Clone()
+
+	table.Reset()
+	params := table.filterParams()
+	if params.Get("tenant_id") != "eq.t1" || params.Has("name") || table.limit != 0 {
+		t.Errorf("Reset should keep only default filters, got %v (limit %d)", params, table.limit)
+	}
+	if got := clone.filterParams(); got.Get("tenant_id") != "eq.t1" || got.Get("name") != "eq.alpha" {
+		t.Errorf("Clone lost filters after Reset of original: %v", got)
+	}
+}
+
+func TestApplyFrom(t *testing.T) {
+	client := NewClient(Config{})
+	base := client.Table("base").Eq("tenant_id", 42).OrderBy("created_at", "desc").Limit(5)
+	projects := client.TableWithDefaults("projects", Eq("archived", false)).Eq("stale", true).ApplyFrom(base)
+
+	params := projects.selectParams()
+	if projects.tableName != "projects" {
+		t.Errorf("tableName = %q", projects.tableName)
+	}
+	want := map[string]string{"tenant_id": "eq.42", "archived": "eq.false", "order": "created_at.desc", "limit": "5"}
+	for k, v := range want {
+		if got := params.Get(k); got != v {
+			t.Errorf("param %s = %q, want %q", k, got, v)
+		}
+	}
+	if params.Has("stale") {
+		t.Error("ApplyFrom kept dst's own filters")
+	}
+	base.Eq("extra", 1)
+	if projects.selectParams().Has("extra") {
+		t.Error("ApplyFrom shares filter storage with src")
+	}
+}
+
+func TestApplyFiltersFromURL(t *testing.T) {
+	u, _ := url.Parse("/admin/users?name=eq.Alice&age=gte.18&order=created_at.desc&limit=10&page=2")
+	table, err := NewClient(Config{}).Table("users").ApplyFiltersFromURL(u)
+	if err != nil {
+		t.Fatalf("ApplyFiltersFromURL failed: %v", err)
+	}
+	params := table.selectParams()
+	for k, v := range map[string]string{"name": "eq.Alice", "age": "gte.18", "order": "created_at.desc", "limit": "10"} {
+		if got := params.Get(k); got != v {
+			t.Errorf("param %s = %q, want %q", k, got, v)
+		}
+	}
+	if params.Has("page") {
+		t.Errorf("unknown param page should be ignored")
+	}
+
+	if _, err := NewClient(Config{}).Table("users").StrictParsing(true).ApplyFiltersFromURL(u); err == nil {
+		t.Errorf("expected error for unknown param in strict mode")
+	}
+
+	// Params are applied in key order, so the same URL always builds the same query.
+	u, _ = url.Parse("/admin/users?status=not.eq.archived&name=eq.Alice&age=gte.18&zip=like.9*&city=eq.Oslo")
+	for i := 0; i < 5; i++ {
+		table, _ := NewClient(Config{}).Table("users").ApplyFiltersFromURL(u)
+		var got []string
+		for _, f := range table.filters {
+			got = append(got, filterString(f))
+		}
+		if want := "age=gte.18 city=eq.Oslo name=eq.Alice status=not.eq.archived zip=like.9*"; strings.Join(got, " ") != want {
+			t.Fatalf("filters = %v, want %s", got, want)
+		}
+	}
+}
+
+func TestInAnyFiltersTags(t *testing.T) {
+	table := NewClient(Config{}).Table("posts").InAny("tags", "golang")
+	if got := table.filterParams().Get("tags"); got != `cs.{"golang"}` {
+		t.Errorf("tags param = %q, want %q", got, `cs.{"golang"}`)
+	}
+
+	quoted := InAny("tags", `say "hi"`).toQuery()
+	if want := `tags.cs.{"say \"hi\""}`; quoted != want {
+		t.Errorf("toQuery() = %q, want %q", quoted, want)
+	}
+}
+
+func TestInQuotesReservedCharacters(t *testing.T) {
+	name := "Tim O'Reilly"
+	cases := []struct {
+		values []interface{}
+		want   string
+	}{
+		{[]interface{}{"O'Reilly"}, `name.in.(O'Reilly)`},
+		{[]interface{}{"O'Reilly, Tim", "plain"}, `name.in.("O'Reilly, Tim",plain)`},
+		{[]interface{}{&name, "f(x)"}, `name.in.("Tim O'Reilly","f(x)")`},
+		{[]interface{}{`say "hi"`, 3, nil}, `name.in.("say \"hi\"",3,null)`},
+	}
+	for _, c := range cases {
+		if got := In("name", c.values).toQuery(); got != c.want {
+			t.Errorf("In(%v).toQuery() = %q, want %q", c.values, got, c.want)
+		}
+	}
+
+	got := splitInList(`"O'Reilly, Tim",plain`)
+	if len(got) != 2 || got[0] != "O'Reilly, Tim" || got[1] != "plain" {
+		t.Errorf("quoted list parsed as %q", got)
+	}
+}
+
+func TestTypedInFilters(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	cases := map[string]Filter{
+		`id.in.(1,-2,300)`:               InInt64s("id", []int64{1, -2, 300}),
+		`score.in.(1.5,2)`:               InFloat64s("score", []float64{1.5, 2}),
+		`at.in.("2024-05-01T12:30:00Z")`: InTimes("at", []time.Time{ts}),
+
+		`user_id.in.(6f1c5b0e-8d1a-4b8e-9c1e-2f7d3a4b5c6d,0B4B4BD5-5D3A-4A6B-9A2E-6F6F1B0C6D11)`: InUUIDs("user_id", []string{"6f1c5b0e-8d1a-4b8e-9c1e-2f7d3a4b5c6d", "0B4B4BD5-5D3A-4A6B-9A2E-6F6F1B0C6D11"}),
+	}
+	for want, f := range cases {
+		if got := f.toQuery(); got != want {
+			t.Errorf("toQuery() = %q, want %q", got, want)
+		}
+	}
+
+	// A query using InUUIDs with an invalid value fails without sending a request, however
+	// deeply the filter is nested.
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+	client := NewClient(Config{BaseURL: srv.URL})
+	bad := InUUIDs("user_id", []string{"6f1c5b0e-8d1a-4b8e-9c1e-2f7d3a4b5c6d", "not-a-uuid"})
+	var rows []map[string]interface{}
+	ops := map[string]func(*Table) error{
+		"Select": func(t *Table) error { return t.Select(&rows, "") },
+		"Count":  func(t *Table) error { _, err := t.Count(""); return err },
+		"Delete": func(t *Table) error { return t.Delete("") },
+	}
+	for name, op := range ops {
+		for _, f := range []Filter{bad, Or(Eq("plan", "pro"), Not(bad))} {
+			if err := op(client.Table("tenants").AddFilter(f)); err == nil || !strings.Contains(err.Error(), "not-a-uuid") {
+				t.Errorf("%s with %v = %v, want an error naming the invalid UUID", name, f, err)
+			}
+		}
+	}
+	if _, err := client.Table("tenants").AddFilter(bad).ToJSON(); err == nil {
+		t.Error("ToJSON serialized an invalid filter")
+	}
+	if requests != 0 {
+		t.Errorf("%d requests sent with an invalid filter", requests)
+	}
+}
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker(CircuitBreakerConfig{Threshold: 2, ResetTimeout: time.Minute})
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if !b.allow() {
+			t.Fatalf("request %d rejected while closed", i)
+		}
+		b.record(false)
+	}
+	if b.allow() {
+		t.Fatalf("breaker should be open after %d failures", 2)
+	}
+
+	now = now.Add(time.Minute)
+	if !b.allow() {
+		t.Fatalf("probe should be allowed after reset timeout")
+	}
+	if b.allow() {
+		t.Fatalf("only one probe should be allowed while half-open")
+	}
+	b.record(true)
+	if !b.allow() {
+		t.Fatalf("breaker should close after a successful probe")
+	}
+}
+
+func TestDeduplicateGets(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		<-release
+		w.Write([]byte(`[{"id":"1"}]`))
+	}))
+	defer srv.Close()
+	client := NewClient(Config{BaseURL: srv.URL, DeduplicateGets: true})
+
+	var wg sync.WaitGroup
+	results := make([][]TestTenant, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := client.Table("test_tenants").Select(&results[i], ""); err != nil {
+				t.Errorf("Select failed: %v", err)
+			}
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Errorf("server hit %d times, want 1", n)
+	}
+	for i, rows := range results {
+		if len(rows) != 1 || rows[0].ID != "1" {
+			t.Errorf("caller %d got %v", i, rows)
+		}
+	}
+}
+
+func TestLoggingInterceptor(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			http.Error(w, `{"message":"boom"}`, http.StatusInternalServerError)
+			return
+		}
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte(`[{"id":"1","name":"Acme"}]`))
+	}))
+	defer srv.Close()
+	var buf bytes.Buffer
+	logged := func() []map[string]interface{} {
+		var records []map[string]interface{}
+		dec := json.NewDecoder(&buf)
+		for dec.More() {
+			var rec map[string]interface{}
+			if err := dec.Decode(&rec); err != nil {
+				t.Fatalf("decode log record: %v", err)
+			}
+			records = append(records, rec)
+		}
+		return records
+	}
+	newClient := func(level slog.Level) *Client {
+		cfg := Config{BaseURL: srv.URL, APIKey: "anon"}
+		WithLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: level})))(&cfg)
+		return NewClient(cfg)
+	}
+
+	var tenants []TestTenant
+	if err := newClient(slog.LevelInfo).Table("tenants").Eq("id", "1").Select(&tenants, ""); err != nil || len(tenants) != 1 {
+		t.Fatalf("Select = %v, %v", tenants, err)
+	}
+	records := logged()
+	if len(records) != 1 {
+		t.Fatalf("got %d log records, want 1", len(records))
+	}
+	rec := records[0]
+	if rec["level"] != "INFO" || rec["method"] != "GET" || rec["table"] != "tenants" || rec["status_code"] != float64(200) ||
+		!strings.HasPrefix(fmt.Sprint(rec["url"]), srv.URL+REST_URL+"/tenants?") {
+		t.Errorf("Select log record = %v", rec)
+	}
+	if _, ok := rec["duration_ms"]; !ok {
+		t.Errorf("Select log record has no duration_ms: %v", rec)
+	}
+	if _, ok := rec["response_body"]; ok {
+		t.Errorf("bodies logged at Info level: %v", rec)
+	}
+
+	// At Debug level bodies are logged, truncated to 1 KB, and the caller still gets the
+	// whole response.
+	debug := newClient(slog.LevelDebug)
+	var inserted []map[string]interface{}
+	if err := debug.Table("tenants").InsertMaps([]map[string]interface{}{{"name": strings.Repeat("x", 2*logBodyLimit)}}, ""); err != nil {
+		t.Fatalf("InsertMaps: %v", err)
+	}
+	if err := debug.Table("tenants").Select(&inserted, ""); err != nil || len(inserted) != 1 || inserted[0]["name"] != "Acme" {
+		t.Fatalf("Select with debug logging = %v, %v", inserted, err)
+	}
+	records = logged()
+	if len(records) != 2 {
+		t.Fatalf("got %d debug log records, want 2", len(records))
+	}
+	if body := fmt.Sprint(records[0]["request_body"]); len(body) != logBodyLimit+len("...(truncated)") || !strings.HasSuffix(body, "...(truncated)") {
+		t.Errorf("request_body = %q, want it truncated to %d bytes", body, logBodyLimit)
+	}
+	if records[1]["response_body"] != `[{"id":"1","name":"Acme"}]` {
+		t.Errorf("response_body = %v", records[1]["response_body"])
+	}
+
+	// Server errors log at Error level; transport failures also log the error.
+	newClient(slog.LevelInfo).Table("tenants").Eq("id", "1").Delete("")
+	unreachable := Config{BaseURL: "http://127.0.0.1:1", APIKey: "anon"}
+	WithLogger(slog.New(slog.NewJSONHandler(&buf, nil)))(&unreachable)
+	NewClient(unreachable).Table("tenants").Select(&tenants, "")
+	records = logged()
+	if len(records) != 2 || records[0]["level"] != "ERROR" || records[0]["status_code"] != float64(500) {
+		t.Fatalf("server error log records = %v", records)
+	}
+	if records[1]["level"] != "ERROR" || records[1]["error"] == nil {
+		t.Errorf("transport error log record = %v", records[1])
+	}
+}
+
+func TestDurationToPostgresInterval(t *testing.T) {
+	cases := map[time.Duration]string{
+		24 * time.Hour:                     "24 hours",
+		90 * time.Minute:                   "1 hour 30 minutes",
+		time.Second + 500*time.Millisecond: "1 second 500 milliseconds",
+		0:                                  "0 seconds",
+		-time.Minute:                       "1 minute ago",
+	}
+	for d, want := range cases {
+		if got := DurationToPostgresInterval(d); got != want {
+			t.Errorf("DurationToPostgresInterval(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestDeduplicateGetsCancelledLeader(t *testing.T) {
+	g := newFlightGroup()
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	leaderDone := make(chan error, 1)
+	go func() {
+		_, err := g.do(leaderCtx, "k", func() (*http.Response, error) {
+			close(started)
+			<-leaderCtx.Done()
+			return nil, leaderCtx.Err()
+		})
+		leaderDone <- err
+	}()
+	<-started
+
+	// A caller with its own context gives up when that context ends.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := g.do(ctx, "k", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("waiting caller with an expired context = %v, want its own deadline error", err)
+	}
+
+	// A waiting caller is not failed by the leader's cancellation; it makes the call itself.
+	followerDone := make(chan error, 1)
+	var body []byte
+	go func() {
+		resp, err := g.do(context.Background(), "k", func() (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("rows"))}, nil
+		})
+		if err == nil {
+			body, _ = io.ReadAll(resp.Body)
+		}
+		followerDone <- err
+	}()
+	time.Sleep(20 * time.Millisecond) // Let the follower join the leader's call
+	cancelLeader()
+	if err := <-leaderDone; !errors.Is(err, context.Canceled) {
+		t.Errorf("leader error = %v, want context.Canceled", err)
+	}
+	if err := <-followerDone; err != nil || string(body) != "rows" {
+		t.Errorf("follower = %q, %v; want it to retry after the leader was cancelled", body, err)
+	}
+}
+
+func TestBanUser(t *testing.T) {
+	const userID = "0b4b4bd5-5d3a-4a6b-9a2e-6f6f1b0c6d11"
+	var bodies []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.URL.Path != AUTH_URL+"/admin/users/"+userID {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		w.Write([]byte(`{"id":"` + userID + `"}`))
+	}))
+	defer srv.Close()
+	admin := NewClient(Config{BaseURL: srv.URL, APIKey: "service"}).Auth().Admin()
+
+	if err := admin.BanUser(userID, 36*time.Hour); err != nil {
+		t.Fatalf("BanUser: %v", err)
+	}
+	if err := admin.UnbanUser(userID); err != nil {
+		t.Fatalf("UnbanUser: %v", err)
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("got %d requests, want 2", len(bodies))
+	}
+	// Supabase Auth parses ban_duration with time.ParseDuration.
+	d, err := time.ParseDuration(fmt.Sprint(bodies[0]["ban_duration"]))
+	if err != nil || d != 36*time.Hour || len(bodies[0]) != 1 {
+		t.Errorf("BanUser body = %v (parsed %s, %v)", bodies[0], d, err)
+	}
+	if bodies[1]["ban_duration"] != "none" || len(bodies[1]) != 1 {
+		t.Errorf("UnbanUser body = %v", bodies[1])
+	}
+	if err := admin.BanUser(userID, 0); err == nil {
+		t.Error("BanUser accepted a zero duration")
+	}
+}
+
+func TestNextPageFromLink(t *testing.T) {
+	link := `</admin/users?page=3&per_page=50>; rel="next", </admin/users?page=9&per_page=50>; rel="last"`
+	if got := nextPageFromLink(link); got != "3" {
+		t.Errorf("nextPageFromLink = %q, want %q", got, "3")
+	}
+	if got := nextPageFromLink(`</admin/users?page=9&per_page=50>; rel="last"`); got != "" {
+		t.Errorf("nextPageFromLink on last page = %q, want empty", got)
+	}
+}
+
+func TestMockStorageClient(t *testing.T) {
+	mock := NewMockStorageClient()
+	mock.Expect("GET", "/bucket/avatars").Return([]byte(`{"id":"avatars","name":"avatars","public":true}`), nil)
+	mock.Expect("GET", "/bucket/missing").Return(nil, &APIError{StatusCode: http.StatusNotFound, Body: `{"error":"not found"}`})
+	mock.Expect("POST", "/object/avatars/me.png").Return([]byte(`{"Key":"avatars/me.png"}`), nil)
+	mock.Expect("POST", "/bucket/avatars/empty")
+
+	var storage StorageClientInterface = mock
+	bucket, err := storage.GetBucket("avatars")
+	if err != nil || bucket.Name != "avatars" || !bucket.Public {
+		t.Fatalf("GetBucket = %+v, %v", bucket, err)
+	}
+	if _, err := storage.GetBucket("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetBucket(missing) error = %v, want ErrNotFound", err)
+	}
+	if err := storage.Bucket("avatars").Upload("me.png", strings.NewReader("png"), "image/png", ""); err != nil {
+		t.Errorf("Upload failed: %v", err)
+	}
+	if err := storage.UpdateBucket("avatars", BucketOptions{}); err == nil {
+		t.Error("unexpected UpdateBucket call succeeded")
+	}
+	if err := mock.Verify(); err == nil || !strings.Contains(err.Error(), "POST /bucket/avatars/empty") {
+		t.Errorf("Verify = %v, want unmet empty bucket expectation", err)
+	}
+}
+
+func TestGetOrCreateBucket(t *testing.T) {
+	notFound := &APIError{StatusCode: http.StatusNotFound, Body: `{"error":"Bucket not found"}`}
+	bucketJSON := []byte(`{"id":"avatars","name":"avatars","public":true}`)
+
+	mock := NewMockStorageClient()
+	mock.Expect("GET", "/bucket/avatars").Return(nil, notFound)
+	mock.Expect("POST", "/bucket").Return([]byte(`{"name":"avatars"}`), nil)
+	mock.Expect("GET", "/bucket/avatars").Return(bucketJSON, nil)
+	bucket, created, err := mock.GetOrCreateBucket("avatars", BucketOptions{Public: true})
+	if err != nil || !created || bucket.Name != "avatars" {
+		t.Errorf("GetOrCreateBucket (missing) = %+v, %v, %v", bucket, created, err)
+	}
+
+	mock.Expect("GET", "/bucket/avatars").Return(bucketJSON, nil)
+	bucket, created, err = mock.GetOrCreateBucket("avatars", BucketOptions{Public: true})
+	if err != nil || created || bucket.Name != "avatars" {
+		t.Errorf("GetOrCreateBucket (existing) = %+v, %v, %v", bucket, created, err)
+	}
+	if err := mock.Verify(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUploadFromURL(t *testing.T) {
+	var uploaded, uploadedType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cdn/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("png-bytes"))
+		case "/cdn/huge.bin":
+			// Flushing forces a chunked response with no Content-Length.
+			for i := 0; i < 4; i++ {
+				w.Write([]byte(strings.Repeat("x", 1024)))
+				w.(http.Flusher).Flush()
+			}
+		case STORAGE_URL + "/object/assets/logo.png":
+			b, _ := io.ReadAll(r.Body)
+			uploaded, uploadedType = string(b), r.Header.Get("Content-Type")
+			w.Write([]byte(`{"Key":"assets/logo.png"}`))
+		default:
+			io.Copy(io.Discard, r.Body)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	bucket := NewClient(Config{BaseURL: srv.URL, APIKey: "key"}).Storage().Bucket("assets")
+
+	if err := bucket.UploadFromURL("logo.png", srv.URL+"/cdn/logo.png", 1024, ""); err != nil {
+		t.Fatalf("UploadFromURL failed: %v", err)
+	}
+	if uploaded != "png-bytes" || uploadedType != "image/png" {
+		t.Errorf("uploaded %q as %q", uploaded, uploadedType)
+	}
+	err := bucket.UploadFromURL("huge.bin", srv.URL+"/cdn/huge.bin", 1024, "")
+	if err == nil || !strings.Contains(err.Error(), "size limit") {
+		t.Errorf("UploadFromURL over the size limit error = %v", err)
+	}
+}
+
+func TestCreateUploadURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.EscapedPath() != STORAGE_URL+"/object/upload/sign/avatars/users/a%20b.png" {
+			t.Errorf("request = %s %s", r.Method, r.URL.EscapedPath())
+		}
+		w.Write([]byte(`{"url":"/object/upload/sign/avatars/users/a%20b.png?token=tok123"}`))
+	}))
+	defer srv.Close()
+	bucket := NewClient(Config{BaseURL: srv.URL, APIKey: "key"}).Storage().Bucket("avatars")
+
+	u, err := bucket.CreateUploadURL("users/a b.png", 600, "")
+	if err != nil {
+		t.Fatalf("CreateUploadURL failed: %v", err)
+	}
+	want := srv.URL + STORAGE_URL + "/object/upload/sign/avatars/users/a%20b.png?token=tok123"
+	if u.SignedURL != want || u.Token != "tok123" || u.Path != "users/a b.png" {
+		t.Errorf("CreateUploadURL = %+v", u)
+	}
+}
+
+func TestNewTestClientWithFakeHandler(t *testing.T) {
+	fake := NewFakeSupabaseHandler()
+	fake.Seed("test_tenants", map[string]interface{}{"id": "1", "user_id": "u1", "name": "Seeded", "plan": "free", "max_users": 5})
+	client, cleanup := NewTestClient(fake)
+	defer cleanup()
+
+	inserted := []TestTenant{{ID: "2", UserID: "u1", Name: "Inserted", Plan: "free", MaxUsers: 50}}
+	if err := client.Table("test_tenants").Insert(&inserted, ""); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	var tenants []TestTenant
+	if err := client.Table("test_tenants").Eq("user_id", "u1").Gt("max_users", 10).Select(&tenants, ""); err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+	if len(tenants) != 1 || tenants[0].Name != "Inserted" {
+		t.Fatalf("Select = %+v, want only the inserted tenant", tenants)
+	}
+	if err := client.Table("test_tenants").Eq("id", "1").Update(map[string]interface{}{"plan": "pro"}, nil, ""); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if rows := fake.Rows("test_tenants"); rows[0]["plan"] != "pro" || rows[1]["plan"] != "free" {
+		t.Errorf("rows after update = %v", rows)
+	}
+	if n, err := client.Table("test_tenants").Count(""); err != nil || n != 2 {
+		t.Errorf("Count = %d, %v; want 2", n, err)
+	}
+	var apiErr *APIError
+	err := client.Table("test_tenants").Like("name", "S%").Select(&tenants, "")
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("unsupported filter error = %v, want 400 APIError", err)
+	}
+}
+
+func TestGenericInvoke(t *testing.T) {
+	type greetRequest struct {
+		Name  string `json:"name"`
+		Times int    `json:"times"`
+	}
+	type greetResponse struct {
+		Message string   `json:"message"`
+		Lines   []string `json:"lines"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != FUNCTIONS_URL+"/greet" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		var req greetRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		res := greetResponse{Message: "hello " + req.Name}
+		for i := 0; i < req.Times; i++ {
+			res.Lines = append(res.Lines, req.Name)
+		}
+		json.NewEncoder(w).Encode(res)
+	}))
+	defer srv.Close()
+	functions := NewClient(Config{BaseURL: srv.URL, APIKey: "key"}).Functions()
+
+	res, err := Invoke[greetRequest, greetResponse](functions, "greet", greetRequest{Name: "Ada", Times: 2}, "")
+	if err != nil {
+		t.Fatalf("Invoke failed: %v", err)
+	}
+	if res.Message != "hello Ada" || len(res.Lines) != 2 {
+		t.Errorf("Invoke = %+v", res)
+	}
+}
+
+func TestRealtimeGetChannel(t *testing.T) {
+	rt := NewClient(Config{}).Realtime()
+	if ch, ok := rt.GetChannel("todos"); ok || ch != nil {
+		t.Fatalf("GetChannel before creation = %v, %v", ch, ok)
+	}
+	todos := rt.Channel("todos")
+	rt.Channel("alerts")
+	if ch, ok := rt.GetChannel("todos"); !ok || ch != todos {
+		t.Errorf("GetChannel(todos) = %v, %v; want the created channel", ch, ok)
+	}
+	channels := rt.Channels()
+	if len(channels) != 2 || channels[0].Name() != "alerts" || channels[1].Name() != "todos" {
+		t.Errorf("Channels() = %v", channels)
+	}
+}
+
+func TestRealtimeStatusOnFailedConnect(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	rt := NewClient(Config{BaseURL: srv.URL}).Realtime()
+	var statuses []ConnectionStatus
+	rt.OnStatusChange(func(s ConnectionStatus) { statuses = append(statuses, s) })
+
+	if err := rt.Connect(context.Background()); err == nil {
+		t.Fatal("Connect to a non-WebSocket server succeeded")
+	}
+	if len(statuses) != 2 || statuses[0] != StatusConnecting || statuses[1] != StatusClosed {
+		t.Errorf("status transitions = %v, want [connecting closed]", statuses)
+	}
+	if rt.Status() != StatusClosed {
+		t.Errorf("Status() = %v, want closed", rt.Status())
+	}
+}
+
+func TestRPCExecuteCtx(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var args map[string]int
+		json.NewDecoder(r.Body).Decode(&args)
+		switch r.URL.Path {
+		case REST_URL + "/rpc/add":
+			json.NewEncoder(w).Encode(args["a"] + args["b"])
+		case REST_URL + "/rpc/withdraw":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":"P0001","message":"insufficient funds","details":"balance is 5","hint":"top up first"}`))
+		}
+	}))
+	defer srv.Close()
+	client := NewClient(Config{BaseURL: srv.URL})
+
+	var sum int
+	if err := client.RPC("add", map[string]int{"a": 2, "b": 3}, "").ExecuteCtx(context.Background(), &sum); err != nil || sum != 5 {
+		t.Errorf("add = %d, %v; want 5", sum, err)
+	}
+
+	err := client.RPC("withdraw", map[string]int{"amount": 10}, "").ExecuteCtx(context.Background(), nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("error = %v, want *APIError", err)
+	}
+	if apiErr.Code != "P0001" || apiErr.Message != "insufficient funds" || apiErr.Details != "balance is 5" || apiErr.Hint != "top up first" {
+		t.Errorf("APIError fields = %+v", apiErr)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := client.RPC("add", nil, "").ExecuteCtx(ctx, &sum); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled ExecuteCtx error = %v, want context.Canceled", err)
+	}
+}
+
+func TestBatchExecute(t *testing.T) {
+	fake := NewFakeSupabaseHandler()
+	client, cleanup := NewTestClient(fake)
+	defer cleanup()
+
+	prefer := http.Header{"Prefer": {"return=representation"}}
+	results, err := client.Batch().
+		Add(BatchOp{Method: "POST", Path: REST_URL + "/items", Body: []map[string]interface{}{{"id": 1}, {"id": 2}}}).
+		Add(BatchOp{Method: "DELETE", Path: REST_URL + "/items?id=eq.1", Headers: prefer}).
+		Add(BatchOp{Method: "GET", Path: REST_URL + "/items?id=like.1"}).
+		Execute("")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	if results[0].StatusCode != http.StatusCreated || results[1].StatusCode != http.StatusOK || results[2].StatusCode != http.StatusBadRequest {
+		t.Errorf("status codes = %d, %d, %d", results[0].StatusCode, results[1].StatusCode, results[2].StatusCode)
+	}
+	if string(results[1].Body) != `[{"id":1}]`+"\n" {
+		t.Errorf("delete body = %q", results[1].Body)
+	}
+	if rows := fake.Rows("items"); len(rows) != 1 {
+		t.Errorf("rows after batch = %v", rows)
+	}
+}
+
+func TestPostgrestError(t *testing.T) {
+	body := []byte(`{"code":"42P01","message":"relation \"public.missing\" does not exist","details":null,"hint":null}`)
+	err := statusError("select", http.StatusNotFound, body)
+
+	var pgErr *PostgrestError
+	if !errors.As(err, &pgErr) {
+		t.Fatalf("errors.As(%v, *PostgrestError) = false", err)
+	}
+	if pgErr.Code != "42P01" || pgErr.Message != `relation "public.missing" does not exist` {
+		t.Errorf("PostgrestError = %+v", pgErr)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("errors.Is(%v, ErrNotFound) = false", err)
+	}
+	if want := `supabase: relation "public.missing" does not exist (42P01)`; pgErr.Error() != want {
+		t.Errorf("Error() = %q, want %q", pgErr.Error(), want)
+	}
+
+	if errors.As(statusError("upload", http.StatusBadGateway, []byte("<html>bad gateway</html>")), &pgErr) {
+		t.Error("non-JSON body extracted as PostgrestError")
+	}
+}
+
+func TestTableWithOptionsFirstLast(t *testing.T) {
+	var orders []string
+	var onConflict string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			onConflict = r.URL.Query().Get("on_conflict")
+			return
+		}
+		orders = append(orders, r.URL.Query().Get("order"))
+		if r.URL.Query().Get("limit") != "1" || r.Header.Get("Accept") != "application/vnd.pgrst.object+json" {
+			t.Errorf("not a single-row request: %s %v", r.URL.RawQuery, r.Header)
+		}
+		w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer srv.Close()
+	client := NewClient(Config{BaseURL: srv.URL})
+	table := client.TableWithOptions("tenants", TableOptions{PrimaryKey: "tenant_key"})
+
+	var tenant TestTenant
+	if err := table.First(&tenant, ""); err != nil || tenant.ID != "1" {
+		t.Fatalf("First = %+v, %v", tenant, err)
+	}
+	if err := table.Last(&tenant, ""); err != nil {
+		t.Fatalf("Last failed: %v", err)
+	}
+	ordered := client.Table("tenants").Order(OrderOption{Field: "created_at", NullsFirst: true})
+	if err := ordered.Last(&tenant, ""); err != nil {
+		t.Fatalf("Last with order failed: %v", err)
+	}
+	want := []string{"tenant_key.asc", "tenant_key.desc", "created_at.desc.nullslast"}
+	for i := range want {
+		if i >= len(orders) || orders[i] != want[i] {
+			t.Errorf("orders = %q, want %q", orders, want)
+			break
+		}
+	}
+	if ordered.limit != 0 || ordered.orders[0].direction != "asc" {
+		t.Error("Last modified the table")
+	}
+
+	if err := table.Upsert(map[string]string{"tenant_key": "k"}, "", ""); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if onConflict != "tenant_key" {
+		t.Errorf("on_conflict = %q, want tenant_key", onConflict)
+	}
+}
+
+func TestImportUsers(t *testing.T) {
+	const existingID = "6f1c5b0e-8d1a-4b8e-9c1e-2f7d3a4b5c6d"
+	var created, updated []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case r.Method == "POST" && body["email"] == "taken@example.com":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"code":422,"error_code":"email_exists","msg":"A user with this email address has already been registered"}`))
+		case r.Method == "POST":
+			created = append(created, body)
+			w.Write([]byte(`{}`))
+		case r.Method == "GET":
+			w.Write([]byte(`{"users":[{"id":"` + existingID + `","email":"taken@example.com"}]}`))
+		case r.Method == "PUT" && r.URL.Path == AUTH_URL+"/admin/users/"+existingID:
+			updated = append(updated, body)
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer srv.Close()
+	admin := NewClient(Config{BaseURL: srv.URL, APIKey: "service"}).Auth().Admin()
+
+	confirmed := time.Now()
+	users := []ImportUserRequest{
+		{Email: "new@example.com", PasswordHash: "$2a$10$hash", EmailConfirmedAt: &confirmed},
+		{Email: "taken@example.com", PasswordHash: "$2a$10$other"},
+		{},
+	}
+	res, err := admin.ImportUsers(users, false)
+	if err != nil {
+		t.Fatalf("ImportUsers failed: %v", err)
+	}
+	if res.ImportedCount != 1 || len(res.Errors) != 2 || res.Errors[0].Index != 1 || res.Errors[1].Index != 2 {
+		t.Errorf("ImportUsers without upsert = %+v", res)
+	}
+	if created[0]["password_hash"] != "$2a$10$hash" || created[0]["email_confirm"] != true {
+		t.Errorf("created user body = %v", created[0])
+	}
+
+	res, err = admin.ImportUsers(users[:2], true)
+	if err != nil || res.ImportedCount != 2 || len(res.Errors) != 0 {
+		t.Errorf("ImportUsers with upsert = %+v, %v", res, err)
+	}
+	if len(updated) != 1 || updated[0]["password_hash"] != "$2a$10$other" {
+		t.Errorf("updated = %v", updated)
+	}
+}
+
+func TestGeneratePKCEPair(t *testing.T) {
+	// Test vector from RFC 7636, appendix B.
+	if got := pkceChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"); got != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("pkceChallenge = %q", got)
+	}
+	verifier, challenge, err := GeneratePKCEPair()
+	if err != nil {
+		t.Fatalf("GeneratePKCEPair failed: %v", err)
+	}
+	if len(verifier) < 43 || challenge != pkceChallenge(verifier) {
+		t.Errorf("GeneratePKCEPair = %q, %q", verifier, challenge)
+	}
+}
+
+func TestChannelStateWithoutConnection(t *testing.T) {
+	ch := NewClient(Config{}).Realtime().Channel("room")
+	var transitions []string
+	ch.OnStateChange(func(old, new ChannelState) { transitions = append(transitions, old.String()+"->"+new.String()) })
+
+	if err := ch.Broadcast("ping", nil); !errors.Is(err, ErrChannelNotJoined) {
+		t.Errorf("Broadcast on a new channel = %v, want ErrChannelNotJoined", err)
+	}
+	if err := ch.Subscribe(context.Background()); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Subscribe without connection = %v, want ErrNotConnected", err)
+	}
+	if ch.State() != ChannelErrored {
+		t.Errorf("State() = %v, want errored", ch.State())
+	}
+	if want := "closed->subscribing,subscribing->errored"; strings.Join(transitions, ",") != want {
+		t.Errorf("transitions = %v, want %s", transitions, want)
+	}
+}
+
+func TestFunctionsAdminList(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/projects/abcdefghij/functions" || r.Header.Get("Authorization") != "Bearer sbp_token" {
+			t.Errorf("request = %s with %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`[{"id":"f1","slug":"hello","name":"hello","status":"ACTIVE","version":3,"verify_jwt":true,"created_at":1700000000000,"updated_at":1700000360000}]`))
+	}))
+	defer srv.Close()
+	client := NewClient(Config{BaseURL: "https://abcdefghij.supabase.co", ManagementAPIKey: "sbp_token", ManagementURL: srv.URL})
+
+	functions, err := client.Functions().Admin().List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(functions) != 1 || functions[0].Slug != "hello" || !functions[0].VerifyJWT || functions[0].CreatedAt.Unix() != 1700000000 {
+		t.Errorf("List = %+v", functions)
+	}
+
+	local := NewClient(Config{BaseURL: "http://localhost:54321", ManagementAPIKey: "sbp_token"})
+	if _, err := local.Functions().Admin().List(); err == nil {
+		t.Error("List without a project ref succeeded")
+	}
+}
+
+func TestManagementClientProjects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sbp_token" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		switch r.URL.Path {
+		case "/projects":
+			w.Write([]byte(`[{"id":"abcdefghij","ref":"abcdefghij","organization_id":"org","name":"demo","region":"us-east-1","status":"ACTIVE_HEALTHY","created_at":"2024-01-02T03:04:05Z","database":{"host":"db.abcdefghij.supabase.co","version":"15.1"}}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Project not found"}`))
+		}
+	}))
+	defer srv.Close()
+	m := NewManagementClient("sbp_token")
+	m.client.ManagementURL = srv.URL
+
+	projects, err := m.ListProjects()
+	if err != nil {
+		t.Fatalf("ListProjects failed: %v", err)
+	}
+	if len(projects) != 1 || projects[0].Ref != "abcdefghij" || projects[0].Database.Version != "15.1" || projects[0].CreatedAt.Year() != 2024 {
+		t.Errorf("ListProjects = %+v", projects)
+	}
+	if _, err := m.GetProject("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetProject error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestPing(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != REST_URL+"/" || r.URL.Query().Get("limit") != "0" {
+			t.Errorf("request = %s", r.URL)
+		}
+		if r.Header.Get("apikey") != "good-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"Invalid API key"}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	if err := NewClient(Config{BaseURL: srv.URL, APIKey: "good-key"}).Ping(context.Background()); err != nil {
+		t.Errorf("Ping failed: %v", err)
+	}
+	err := NewClient(Config{BaseURL: srv.URL, APIKey: "bad-key"}).Ping(context.Background())
+	if !errors.Is(err, ErrUnauthorized) || !strings.Contains(err.Error(), "invalid API key") {
+		t.Errorf("Ping with bad key = %v", err)
+	}
+	if err := NewClient(Config{BaseURL: "http://127.0.0.1:1"}).Ping(context.Background()); err == nil || !strings.Contains(err.Error(), "cannot reach") {
+		t.Errorf("Ping unreachable = %v", err)
+	}
+}
+
+func TestToSQL(t *testing.T) {
+	client := NewClient(Config{BaseURL: "http://localhost"})
+	got := client.Table("users").
+		SelectColumns("id", "name").
+		Eq("name", "O'Brien").
+		Gt("age", 18).
+		Or(Eq("plan", "pro"), In("region", []interface{}{"eu", 3, nil})).
+		Not(Like("email", "%test%")).
+		NotEq("deleted_at", nil).
+		OrderBy("created_at", "desc").
+		Limit(10).
+		Offset(20).
+		ToSQL()
+	want := "SELECT id, name FROM users WHERE name = 'O''Brien' AND age > 18 AND (plan = 'pro' OR region IN ('eu', 3, NULL))" +
+		" AND NOT (email LIKE '%test%') AND deleted_at IS NOT NULL ORDER BY created_at DESC LIMIT 10 OFFSET 20"
+	if got != want {
+		t.Errorf("ToSQL =\n%s\nwant\n%s", got, want)
+	}
+	if got := client.Table("users").ToSQL(); got != "SELECT * FROM users" {
+		t.Errorf("ToSQL = %q", got)
+	}
+}
+
+func TestJSONBSet(t *testing.T) {
+	got := JSONBSet("settings", "theme.color", "it's dark")
+	want := `jsonb_set(settings, '{theme,color}', '"it''s dark"')`
+	if len(got) != 1 || got["settings"] != want {
+		t.Errorf("JSONBSet = %v, want settings: %s", got, want)
+	}
+}
+
+func TestSignInWithOAuth(t *testing.T) {
+	client := NewClient(Config{BaseURL: "https://abc.supabase.co"})
+	res, err := client.Auth().SignInWithOAuth("github", OAuthOptions{
+		RedirectTo:  "https://app.example.com/callback?next=/home",
+		Scopes:      []string{"repo", "read:user"},
+		QueryParams: map[string]string{"access_type": "offline"},
+	})
+	if err != nil {
+		t.Fatalf("SignInWithOAuth failed: %v", err)
+	}
+	u, err := url.Parse(res.URL)
+	if err != nil {
+		t.Fatalf("invalid URL %q: %v", res.URL, err)
+	}
+	q := u.Query()
+	if u.Path != AUTH_URL+"/authorize" || q.Get("provider") != "github" || q.Get("scopes") != "repo read:user" || q.Get("access_type") != "offline" {
+		t.Errorf("URL = %s", res.URL)
+	}
+	if q.Get("code_challenge") != pkceChallenge(res.CodeVerifier) || q.Get("code_challenge_method") != "s256" {
+		t.Errorf("code challenge does not match the verifier: %s", res.URL)
+	}
+	redirect, _ := url.Parse(q.Get("redirect_to"))
+	if redirect.Query().Get("state") != res.State || redirect.Query().Get("next") != "/home" || res.State == "" {
+		t.Errorf("redirect_to = %q, state %q", q.Get("redirect_to"), res.State)
+	}
+}
+
+func TestSelectMaps(t *testing.T) {
+	fake := NewFakeSupabaseHandler()
+	fake.Seed("items",
+		map[string]interface{}{"id": 1, "name": "a", "tags": []interface{}{"x"}},
+		map[string]interface{}{"id": 2, "name": "b"},
+	)
+	client, cleanup := NewTestClient(fake)
+	defer cleanup()
+
+	rows, err := client.Table("items").SelectMaps("")
+	if err != nil || len(rows) != 2 || rows[0]["name"] != "a" {
+		t.Fatalf("SelectMaps = %v, %v", rows, err)
+	}
+	row, err := client.Table("items").Eq("id", 2).SelectOneMaps("")
+	if err != nil || row["name"] != "b" {
+		t.Errorf("SelectOneMaps = %v, %v", row, err)
+	}
+	if _, err := client.Table("items").Eq("id", 3).SelectOneMaps(""); !errors.Is(err, ErrNoRows) {
+		t.Errorf("SelectOneMaps with no match = %v, want ErrNoRows", err)
+	}
+	if _, err := client.Table("items").SelectOneMaps(""); !errors.Is(err, ErrTooManyRows) {
+		t.Errorf("SelectOneMaps with two matches = %v, want ErrTooManyRows", err)
+	}
+}
+
+func TestSignInAnonymously(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		data, _ := body["data"].(map[string]interface{})
+		if r.URL.Path != AUTH_URL+"/signup" || len(body) != 1 || data["theme"] != "dark" {
+			t.Errorf("request = %s %v", r.URL.Path, body)
+		}
+		w.Write([]byte(`{"access_token":"jwt","refresh_token":"r","user":{"id":"u1","is_anonymous":true}}`))
+	}))
+	defer srv.Close()
+	client := NewClient(Config{BaseURL: srv.URL, APIKey: "anon"})
+
+	res, err := client.Auth().SignInAnonymously(AnonymousSignInOptions{Data: map[string]interface{}{"theme": "dark"}})
+	if err != nil {
+		t.Fatalf("SignInAnonymously failed: %v", err)
+	}
+	if res.AccessToken != "jwt" || res.User == nil || !res.User.IsAnonymous {
+		t.Errorf("SignInAnonymously = %+v", res)
+	}
+}
+
+func TestLinkIdentity(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != AUTH_URL+"/user/identities/authorize" || q.Get("provider") != "google" ||
+			q.Get("redirect_to") != "https://app.example.com/settings" || r.Header.Get("Authorization") != "Bearer jwt" {
+			t.Errorf("request = %s with %q", r.URL, r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"url":"https://accounts.google.com/o/oauth2/auth?state=abc"}`))
+	}))
+	defer srv.Close()
+	client := NewClient(Config{BaseURL: srv.URL, APIKey: "anon"})
+
+	u, err := client.Auth().LinkIdentity("google", OAuthOptions{RedirectTo: "https://app.example.com/settings"}, "jwt")
+	if err != nil || u != "https://accounts.google.com/o/oauth2/auth?state=abc" {
+		t.Errorf("LinkIdentity = %q, %v", u, err)
+	}
+}
+
+func TestCopyAcrossBuckets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.Method != "POST" || r.URL.Path != STORAGE_URL+"/object/copy" || body["bucketId"] != "temp" ||
+			body["sourceKey"] != "a/b.png" || body["destinationBucket"] != "permanent" || body["destinationKey"] != "c.png" {
+			t.Errorf("request = %s %s %v", r.Method, r.URL.Path, body)
+		}
+		w.Write([]byte(`{"Key":"permanent/c.png"}`))
+	}))
+	defer srv.Close()
+	client := NewClient(Config{BaseURL: srv.URL, APIKey: "service"})
+
+	if err := client.Storage().CopyAcrossBuckets("temp", "a/b.png", "permanent", "c.png", ""); err != nil {
+		t.Errorf("CopyAcrossBuckets failed: %v", err)
+	}
+}
+
+func TestSelectOne(t *testing.T) {
+	fake := NewFakeSupabaseHandler()
+	fake.Seed("test_tenants",
+		map[string]interface{}{"id": "1", "user_id": "u1", "name": "One", "plan": "free"},
+		map[string]interface{}{"id": "2", "user_id": "u1", "name": "Two", "plan": "pro"},
+	)
+	client, cleanup := NewTestClient(fake)
+	defer cleanup()
+
+	var tenant TestTenant
+	if err := client.Table("test_tenants").Eq("plan", "pro").SelectOne(&tenant, ""); err != nil || tenant.Name != "Two" {
+		t.Errorf("SelectOne = %+v, %v", tenant, err)
+	}
+	if err := client.Table("test_tenants").Eq("plan", "team").SelectOne(&tenant, ""); !errors.Is(err, ErrNoRows) {
+		t.Errorf("SelectOne with no match = %v, want ErrNoRows", err)
+	}
+	table := client.Table("test_tenants").Eq("user_id", "u1")
+	if err := table.SelectOne(&tenant, ""); !errors.Is(err, ErrMultipleRows) {
+		t.Errorf("SelectOne with two matches = %v, want ErrMultipleRows", err)
+	}
+	var tenants []TestTenant
+	if err := table.Select(&tenants, ""); err != nil || len(tenants) != 2 {
+		t.Errorf("Select after SelectOne = %d rows, %v; SelectOne must not modify the table", len(tenants), err)
+	}
+
+	// The other single-row reads share SelectOne's single-object mode and errors.
+	if _, err := table.SelectOneMaps(""); !errors.Is(err, ErrMultipleRows) {
+		t.Errorf("SelectOneMaps with two matches = %v, want ErrMultipleRows", err)
+	}
+	none := client.Table("test_tenants").Eq("plan", "team")
+	singles := map[string]func() error{
+		"SelectOneMaps": func() error { _, err := none.SelectOneMaps(""); return err },
+		"First":         func() error { return none.First(&tenant, "") },
+		"Last":          func() error { return none.Last(&tenant, "") },
+	}
+	for name, read := range singles {
+		if err := read(); !errors.Is(err, ErrNoRows) {
+			t.Errorf("%s with no match = %v, want ErrNoRows", name, err)
+		}
+	}
+}
+
+func TestListSessionsForUser(t *testing.T) {
+	const userID = "0b4b4bd5-5d3a-4a6b-9a2e-6f6f1b0c6d11"
+	var revoked string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == AUTH_URL+"/admin/users/"+userID+"/sessions":
+			w.Write([]byte(`[{"id":"s1","user_id":"` + userID + `","aal":"aal2","factor_id":"f1","ip":"203.0.113.7","user_agent":"curl/8","created_at":"2024-05-01T10:00:00Z","updated_at":"2024-05-01T11:00:00Z","not_after":null}]`))
+		case r.Method == "DELETE":
+			revoked = r.URL.Path
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+	admin := NewClient(Config{BaseURL: srv.URL, APIKey: "service"}).Auth().Admin()
+
+	sessions, err := admin.ListSessionsForUser(userID)
+	if err != nil || len(sessions) != 1 || sessions[0].AAL != "aal2" || sessions[0].IP != "203.0.113.7" || sessions[0].NotAfter != nil {
+		t.Fatalf("ListSessionsForUser = %+v, %v", sessions, err)
+	}
+	if err := admin.RevokeSession(userID, "s1"); err != nil || revoked != AUTH_URL+"/admin/users/"+userID+"/sessions/s1" {
+		t.Errorf("RevokeSession = %v (path %q)", err, revoked)
+	}
+	if err := admin.DeleteAllSessions(userID); err != nil || revoked != AUTH_URL+"/admin/users/"+userID+"/sessions" {
+		t.Errorf("DeleteAllSessions = %v (path %q)", err, revoked)
+	}
+	if _, err := admin.ListSessionsForUser("not-a-uuid"); err == nil {
+		t.Error("ListSessionsForUser accepted an invalid user ID")
+	}
+}
+
+func TestSelectWithHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `W/"abc"`)
+		w.Header().Set("Server-Timing", "db;dur=3")
+		w.Write([]byte(`[{"id":"1","name":"Acme"}]`))
+	}))
+	defer srv.Close()
+	client := NewClient(Config{BaseURL: srv.URL, APIKey: "anon"})
+
+	var tenants []TestTenant
+	header, err := client.Table("test_tenants").SelectWithHeaders(&tenants, "")
+	if err != nil || len(tenants) != 1 || tenants[0].Name != "Acme" {
+		t.Fatalf("SelectWithHeaders = %+v, %v", tenants, err)
+	}
+	if header.Get("ETag") != `W/"abc"` || header.Get("Server-Timing") != "db;dur=3" {
+		t.Errorf("headers = %v", header)
+	}
+}
+
+func TestSSOProviders(t *testing.T) {
+	provider := `{"id":"p1","saml":{"entity_id":"https://idp.example.com","metadata_url":"https://idp.example.com/metadata"},"domains":[{"id":"d1","domain":"example.com"}]}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST " + AUTH_URL + "/admin/sso/providers":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["type"] != "saml" || body["metadata_url"] != "https://idp.example.com/metadata" || body["metadata_xml"] != nil {
+				t.Errorf("create body = %v", body)
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(provider))
+		case "GET " + AUTH_URL + "/admin/sso/providers":
+			w.Write([]byte(`{"items":[` + provider + `]}`))
+		case "DELETE " + AUTH_URL + "/admin/sso/providers/p1":
+			w.Write([]byte(provider))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+	admin := NewClient(Config{BaseURL: srv.URL, APIKey: "service"}).Auth().Admin()
+
+	created, err := admin.CreateSSOProvider(SSOProviderOptions{MetadataURL: "https://idp.example.com/metadata", Domains: []string{"example.com"}})
+	if err != nil || created.ID != "p1" || created.SAML.EntityID != "https://idp.example.com" {
+		t.Fatalf("CreateSSOProvider = %+v, %v", created, err)
+	}
+	providers, err := admin.ListSSOProviders()
+	if err != nil || len(providers) != 1 || providers[0].Domains[0].Domain != "example.com" {
+		t.Errorf("ListSSOProviders = %+v, %v", providers, err)
+	}
+	if err := admin.DeleteSSOProvider("p1"); err != nil {
+		t.Errorf("DeleteSSOProvider failed: %v", err)
+	}
+	if _, err := admin.CreateSSOProvider(SSOProviderOptions{Type: "saml"}); err == nil {
+		t.Error("CreateSSOProvider without metadata succeeded")
+	}
+}
+
+func TestRealtimeRemoveAllChannels(t *testing.T) {
+	rt := NewClient(Config{}).Realtime()
+	rt.Channel("todos")
+	errored := rt.Channel("alerts")
+	errored.setState(ChannelErrored)
+	if n := rt.ChannelCount(); n != 2 {
+		t.Fatalf("ChannelCount = %d, want 2", n)
+	}
+	if err := rt.RemoveAllChannels(); err != nil {
+		t.Errorf("RemoveAllChannels without a connection failed: %v", err)
+	}
+	if n := rt.ChannelCount(); n != 0 || errored.State() != ChannelClosed {
+		t.Errorf("after RemoveAllChannels: ChannelCount = %d, state %v", n, errored.State())
+	}
+}
+
+func TestGetSignedUploadURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != STORAGE_URL+"/object/upload/sign/docs/report.pdf" || r.Header.Get("x-upsert") != "true" {
+			t.Errorf("request = %s with x-upsert %q", r.URL.Path, r.Header.Get("x-upsert"))
+		}
+		w.Write([]byte(`{"url":"/object/upload/sign/docs/report.pdf?token=tok456"}`))
+	}))
+	defer srv.Close()
+	bucket := NewClient(Config{BaseURL: srv.URL, APIKey: "key"}).Storage().Bucket("docs")
+
+	u, err := bucket.GetSignedUploadURL("report.pdf", true, "")
+	if err != nil || u.Token != "tok456" || u.URL != srv.URL+STORAGE_URL+"/object/upload/sign/docs/report.pdf?token=tok456" {
+		t.Errorf("GetSignedUploadURL = %+v, %v", u, err)
+	}
+}
+
+func TestWithServiceRole(t *testing.T) {
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("apikey"))
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+	client := NewClient(Config{BaseURL: srv.URL, APIKey: "anon"})
+	admin := client.WithServiceRole("service")
+
+	var rows []map[string]interface{}
+	admin.Table("t").Select(&rows, "")
+	client.Table("t").Select(&rows, "")
+	if len(keys) != 2 || keys[0] != "service" || keys[1] != "anon" {
+		t.Errorf("apikey headers = %v, want [service anon]", keys)
+	}
+
+	// Passing the client's own key is warned about through the configured logger.
+	var buf bytes.Buffer
+	cfg := Config{BaseURL: srv.URL, APIKey: "anon"}
+	WithLogger(slog.New(slog.NewTextHandler(&buf, nil)))(&cfg)
+	NewClient(cfg).WithServiceRole("anon")
+	if !strings.Contains(buf.String(), "level=WARN") || !strings.Contains(buf.String(), "WithServiceRole") {
+		t.Errorf("configured logger got %q, want a WithServiceRole warning", buf.String())
+	}
+}
+
+func TestSelectWithPlan(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Header.Get("Accept"), "application/vnd.pgrst.plan+text") {
+			w.Write([]byte("Seq Scan on test_tenants  (cost=0.00..1.01 rows=1 width=64)"))
+			return
+		}
+		w.Write([]byte(`[{"id":"1","name":"Acme"}]`))
+	}))
+	defer srv.Close()
+	client := NewClient(Config{BaseURL: srv.URL, APIKey: "anon"})
+
+	var tenants []TestTenant
+	var plan string
+	if err := client.Table("test_tenants").Eq("id", "1").SelectWithPlan(&tenants, &plan, ""); err != nil {
+		t.Fatalf("SelectWithPlan failed: %v", err)
+	}
+	if len(tenants) != 1 || !strings.HasPrefix(plan, "Seq Scan") {
+		t.Errorf("SelectWithPlan = %+v, plan %q", tenants, plan)
+	}
+}
+
+func TestDryRunSQL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != REST_URL+"/test_tenants" || r.URL.Query().Get("id") != "eq.1" {
+			t.Errorf("request = %s %s", r.Method, r.URL)
+		}
+		if got := r.Header.Get("Accept"); got != "application/vnd.pgrst.plan+text; options=analyze" {
+			t.Errorf("Accept = %q, want an analyzed text plan", got)
+		}
+		if got := r.Header.Get("Prefer"); got != "tx=rollback" {
+			t.Errorf("Prefer = %q, want tx=rollback", got)
+		}
+		w.Write([]byte("Seq Scan on test_tenants  (actual time=0.010..0.011 rows=1 loops=1)"))
+	}))
+	defer srv.Close()
+	client := NewClient(Config{BaseURL: srv.URL, APIKey: "anon"})
+
+	var plan string
+	if err := client.Table("test_tenants").Eq("id", "1").DryRunSQL(&plan, ""); err != nil {
+		t.Fatalf("DryRunSQL failed: %v", err)
+	}
+	if !strings.Contains(plan, "actual time") {
+		t.Errorf("plan = %q", plan)
+	}
+}
+
+func TestFilterString(t *testing.T) {
+	tests := []struct {
+		filter Filter
+		want   string
+	}{
+		{Gte("age", 18), "age=gte.18"},
+		{Eq("deleted_at", nil), "deleted_at=is.null"},
+		{And(Eq("name", "Alice"), Gte("age", 18)), "and=(name.eq.Alice,age.gte.18)"},
+		{Not(Like("name", "test*")), "name=not.like.test*"},
+		{Not(Not(Eq("plan", "pro"))), "plan=eq.pro"},
+		{NewFilterGroup().Not(Not(Or(Eq("plan", "pro"), Gt("max_users", 5)))).Build(), "or=(plan.eq.pro,max_users.gt.5)"},
+		{Or(Not(Not(Eq("plan", "pro"))), Not(Not(Not(Eq("plan", "free"))))), "or=(plan.eq.pro,plan.not.eq.free)"},
+
+		// Negating a NULL check flips it instead of stacking a second not, and comparisons
+		// against nil that have no NULL equivalent are left out rather than negated.
+		{Not(NotEq("owner", nil)), "owner=is.null"},
+		{Not(Eq("owner", nil)), "owner=not.is.null"},
+		{Or(Not(NotEq("owner", nil)), Eq("plan", "pro")), "or=(owner.is.null,plan.eq.pro)"},
+		{Not(Gt("archived_at", nil)), ""},
+		{Or(Eq("plan", "pro"), Not(Gt("archived_at", nil))), "or=(plan.eq.pro)"},
+		{Not(Or(Gt("archived_at", nil))), ""},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprintf("%v", tt.filter); got != tt.want {
+			t.Errorf("%%v = %q, want %q", got, tt.want)
+		}
+	}
+	table := NewClient(Config{BaseURL: "https://abc.supabase.co"}).Table("users").Eq("id", 7).Limit(1)
+	if got, want := table.String(), "https://abc.supabase.co"+REST_URL+"/users?id=eq.7&limit=1&select=%2A"; got != want {
+		t.Errorf("Table.String() = %q, want %q", got, want)
+	}
+}
+
+func TestAuthGetURL(t *testing.T) {
+	auth := NewClient(Config{BaseURL: "https://abc.supabase.co/"}).Auth()
+	tests := map[string]string{
+		"authorize?provider=github": "https://abc.supabase.co/auth/v1/authorize?provider=github",
+		"/verify":                   "https://abc.supabase.co/auth/v1/verify",
+		"/auth/v1/user":             "https://abc.supabase.co/auth/v1/user",
+		"auth/v1":                   "https://abc.supabase.co/auth/v1",
+		"":                          "https://abc.supabase.co/auth/v1",
+		"auth/v10":                  "https://abc.supabase.co/auth/v1/auth/v10",
+	}
+	for path, want := range tests {
+		if got := auth.GetURL(path); got != want {
+			t.Errorf("GetURL(%q) = %q, want %q", path, got, want)
+		}
+	}
+
+	// Auth requests are built with GetURL, so a trailing slash on BaseURL does not double up.
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	auth = NewClient(Config{BaseURL: srv.URL + "/"}).Auth()
+	auth.GetUser("jwt")
+	auth.Admin().GetHealth()
+	if want := "[/auth/v1/user /auth/v1/health]"; fmt.Sprint(paths) != want {
+		t.Errorf("requested %v, want %s", paths, want)
+	}
+}
+
+func TestTablePage(t *testing.T) {
+	client := NewClient(Config{BaseURL: "http://localhost"})
+	if q := client.Table("t").Page(3, 20).selectParams(); q.Get("offset") != "40" || q.Get("limit") != "20" {
+		t.Errorf("Page(3, 20) = %v", q)
+	}
+	if q := client.Table("t").Page(0, 20).selectParams(); q.Get("offset") != "" || q.Get("limit") != "20" {
+		t.Errorf("Page(0, 20) = %v, want the first page", q)
+	}
+
+	// The page number warning goes to the configured logger.
+	var buf bytes.Buffer
+	cfg := Config{BaseURL: "http://localhost"}
+	WithLogger(slog.New(slog.NewTextHandler(&buf, nil)))(&cfg)
+	NewClient(cfg).Table("t").Page(-1, 20)
+	if !strings.Contains(buf.String(), "level=WARN") || !strings.Contains(buf.String(), "page=-1") {
+		t.Errorf("configured logger got %q, want a page number warning", buf.String())
+	}
+
+	// A page size below 1 fails reads instead of sending a negative offset.
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+	var rows []map[string]interface{}
+	table := NewClient(Config{BaseURL: srv.URL}).Table("t").Page(3, 0)
+	if q := table.selectParams(); q.Has("offset") || q.Has("limit") {
+		t.Errorf("Page(3, 0) = %v, want no pagination", q)
+	}
+	if err := table.Select(&rows, ""); err == nil || requests != 0 {
+		t.Errorf("Select after Page(3, 0) = %v after %d requests, want an error and no request", err, requests)
+	}
+	if err := table.Reset().Select(&rows, ""); err != nil || requests != 1 {
+		t.Errorf("Select after Reset = %v after %d requests", err, requests)
+	}
+}
+
+func TestAuthGetHealth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != AUTH_URL+"/health" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"version":"v2.151.0","name":"GoTrue","description":"GoTrue is a user registration and authentication API"}`))
+	}))
+	defer srv.Close()
+
+	health, err := NewClient(Config{BaseURL: srv.URL, APIKey: "anon"}).Auth().Admin().GetHealth()
+	if err != nil || health.Version != "v2.151.0" || health.Name != "GoTrue" {
+		t.Errorf("GetHealth = %+v, %v", health, err)
+	}
+}
+
+func TestObjectInfo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "HEAD" {
+			t.Errorf("method = %s, want HEAD", r.Method)
+		}
+		switch r.URL.Path {
+		case STORAGE_URL + "/object/docs/report.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Length", "2048")
+			w.Header().Set("ETag", `"abc"`)
+			w.Header().Set("Last-Modified", "Wed, 01 May 2024 10:00:00 GMT")
+			w.Header().Set("Cache-Control", "max-age=3600")
+		case STORAGE_URL + "/object/docs/secret.pdf":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	bucket := NewClient(Config{BaseURL: srv.URL, APIKey: "key"}).Storage().Bucket("docs")
+
+	info, err := bucket.ObjectInfo("report.pdf", "")
+	if err != nil || info.Size != 2048 || info.ContentType != "application/pdf" || info.LastModified.Year() != 2024 || info.CacheControl != "max-age=3600" {
+		t.Errorf("ObjectInfo = %+v, %v", info, err)
+	}
+	if _, err := bucket.ObjectInfo("secret.pdf", ""); !errors.Is(err, ErrForbidden) {
+		t.Errorf("ObjectInfo(secret) = %v, want ErrForbidden", err)
+	}
+	if _, err := bucket.ObjectInfo("missing.pdf", ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ObjectInfo(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFunctionsWithBaseURL(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	client := NewClient(Config{BaseURL: srv.URL, APIKey: "anon"})
+
+	if _, err := client.Functions().WithBaseURL(srv.URL+"/local/functions/v1/").Invoke("hello", nil, ""); err != nil {
+		t.Fatalf("Invoke with base URL failed: %v", err)
+	}
+	if _, err := client.Functions().Invoke("hello", nil, ""); err != nil {
+		t.Fatalf("Invoke failed: %v", err)
+	}
+	if len(paths) != 2 || paths[0] != "/local/functions/v1/hello" || paths[1] != FUNCTIONS_URL+"/hello" {
+		t.Errorf("paths = %v", paths)
+	}
+
+	for base, want := range map[string]string{
+		"http://localhost":       LOCAL_FUNCTIONS_URL,
+		"http://localhost:54321": LOCAL_FUNCTIONS_URL,
+		"http://localhost:8000":  "http://localhost:8000" + FUNCTIONS_URL, // Self-hosted docker-compose
+	} {
+		if got := NewClient(Config{BaseURL: base}).Functions().functionsURL(); got != want {
+			t.Errorf("functionsURL for %s = %q, want %q", base, got, want)
+		}
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+	client := NewClient(Config{BaseURL: srv.URL, APIKey: "anon", MaxBodySize: 64})
+	big := map[string]interface{}{"notes": strings.Repeat("x", 100)}
+
+	if err := client.Table("t").Insert(&big, ""); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("Insert = %v, want ErrBodyTooLarge", err)
+	}
+	if err := client.Table("t").Eq("id", 1).Update(big, nil, ""); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("Update = %v, want ErrBodyTooLarge", err)
+	}
+	if err := client.Table("t").Upsert(big, "id", ""); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("Upsert = %v, want ErrBodyTooLarge", err)
+	}
+	if requests != 0 {
+		t.Errorf("%d requests sent, want none", requests)
+	}
+	if err := client.Table("t").Upsert(map[string]interface{}{"id": 1}, "id", ""); err != nil || requests != 1 {
+		t.Errorf("small Upsert = %v after %d requests", err, requests)
+	}
+}
+
+func TestMatchStruct(t *testing.T) {
+	type Base struct {
+		TenantID string `db:"tenant_id"`
+	}
+	type example struct {
+		Base
+		Plan    string   `db:"plan"`
+		MinAge  int      `db:"age,gte"`
+		Name    string   `json:"name,omitempty"`
+		Regions []string `db:"region,in"`
+		Active  *bool    `json:"active"`
+		Ignored string   `db:"-"`
+		Empty   string   `db:"empty"`
+	}
+	active := false
+	params := NewClient(Config{}).Table("users").MatchStruct(&example{
+		Base:    Base{TenantID: "t1"},
+		Plan:    "pro",
+		MinAge:  18,
+		Regions: []string{"eu", "us"},
+		Active:  &active,
+		Ignored: "x",
+	}).filterParams()
+	want := url.Values{
+		"tenant_id": {"eq.t1"},
+		"plan":      {"eq.pro"},
+		"age":       {"gte.18"},
+		"region":    {"in.(eu,us)"},
+		"active":    {"eq.false"},
+	}
+	if params.Encode() != want.Encode() {
+		t.Errorf("MatchStruct params = %v, want %v", params, want)
+	}
+}
+
+func TestVerifyOTPAuto(t *testing.T) {
+	var bodies []verifyOTPRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body verifyOTPRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		w.Write([]byte(`{"access_token":"jwt"}`))
+	}))
+	defer srv.Close()
+	auth := NewClient(Config{BaseURL: srv.URL, APIKey: "anon"}).Auth()
+
+	for _, contact := range []string{"ada@example.com", "+15551234567", "15551234567"} {
+		if _, err := auth.VerifyOTPAuto(contact, "123456"); err != nil {
+			t.Errorf("VerifyOTPAuto(%q) failed: %v", contact, err)
+		}
+	}
+	if len(bodies) != 3 || bodies[0].Type != "email" || bodies[0].Email != "ada@example.com" ||
+		bodies[1].Type != "sms" || bodies[1].Phone != "+15551234567" || bodies[2].Type != "sms" {
+		t.Errorf("requests = %+v", bodies)
+	}
+	if _, err := auth.VerifyOTPAuto("ada", "123456"); err == nil || len(bodies) != 3 {
+		t.Errorf("VerifyOTPAuto(ada) = %v, want an error without a request", err)
+	}
+}
+
+func TestBucketClientName(t *testing.T) {
+	if name := NewClient(Config{}).Storage().Bucket("avatars").Name(); name != "avatars" {
+		t.Errorf("Name = %q, want avatars", name)
+	}
+}
+
+func TestIncrementCol(t *testing.T) {
+	var params map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != REST_URL+"/rpc/supabase_sdk_increment" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&params)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	client := NewClient(Config{BaseURL: srv.URL, APIKey: "anon"})
+
+	if err := client.Table("posts").Eq("id", 7).DecrementCol("stock", 2, ""); err != nil {
+		t.Fatalf("DecrementCol failed: %v", err)
+	}
+	filter, _ := params["filter"].(map[string]interface{})
+	if params["table_name"] != "posts" || params["col"] != "stock" || params["amount"] != float64(-2) || filter["id"] != float64(7) {
+		t.Errorf("params = %v", params)
+	}
+	if err := client.Table("posts").Gt("id", 7).IncrementCol("views", 1, ""); err == nil {
+		t.Error("IncrementCol with a Gt filter succeeded")
+	}
+	params = nil
+	if err := client.Table("posts").IncrementCol("views", 1, ""); err == nil || params != nil {
+		t.Errorf("IncrementCol without filters = %v (params %v), want an error and no request", err, params)
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"u1","session_id":"s1","aal":"aal1","exp":` + fmt.Sprint(time.Now().Add(time.Hour).Unix()) + `}`))
+	token := "e30." + claims + ".sig"
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":401,"msg":"invalid JWT"}`))
+			return
+		}
+		w.Write([]byte(`{"id":"u1","email":"ada@example.com"}`))
+	}))
+	defer srv.Close()
+	auth := NewClient(Config{BaseURL: srv.URL, APIKey: "anon"}).Auth()
+	store := NewMemorySessionStore()
+
+	handler := NewAuthMiddleware(auth, store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := UserFromContext(r.Context())
+		s, _ := SessionFromContext(r.Context())
+		if !ok || user.Email != "ada@example.com" || s.ID != "s1" || s.NotAfter == nil {
+			t.Errorf("context user = %+v, session = %+v", user, s)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	serve := func(authz string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		if authz != "" {
+			req.Header.Set("Authorization", authz)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := serve("Bearer " + token); code != http.StatusNoContent {
+			t.Fatalf("request %d: status = %d", i, code)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("GetUser called %d times, want 1 (second request from the store)", calls)
+	}
+	if code := serve(""); code != http.StatusUnauthorized {
+		t.Errorf("no token: status = %d", code)
+	}
+	if code := serve("Bearer forged"); code != http.StatusUnauthorized {
+		t.Errorf("forged token: status = %d", code)
+	}
+	if _, err := store.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestTableBeforeHooks(t *testing.T) {
+	fake := NewFakeSupabaseHandler()
+	client, cleanup := NewTestClient(fake)
+	defer cleanup()
+	errNoName := errors.New("name is required")
+	table := client.Table("tenants").ReturnMinimal().
+		BeforeInsert(func(record interface{}) error {
+			if tenant, ok := record.(*TestTenant); ok && tenant.Name == "" {
+				return errNoName
+			}
+			return nil
+		}).
+		BeforeUpdate(func(values map[string]interface{}) error {
+			values["updated_by"] = "admin"
+			return nil
+		})
+
+	if err := table.Insert(&TestTenant{ID: "1"}, ""); !errors.Is(err, errNoName) || len(fake.Rows("tenants")) != 0 {
+		t.Fatalf("Insert without name = %v, rows %v", err, fake.Rows("tenants"))
+	}
+	if err := table.Insert(&TestTenant{ID: "1", Name: "Acme"}, ""); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := table.Clone().Eq("id", "1").Update(map[string]interface{}{"name": "Acme Inc"}, nil, ""); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if rows := fake.Rows("tenants"); len(rows) != 1 || rows[0]["updated_by"] != "admin" {
+		t.Errorf("rows = %v, want updated_by set by the hook", rows)
+	}
+}
+
+func TestRealtimeHeartbeatInterval(t *testing.T) {
+	client := NewClient(Config{})
+	if d := client.Realtime().heartbeatInterval; d != 30*time.Second {
+		t.Errorf("default heartbeat interval = %v, want 30s", d)
+	}
+	rt := client.RealtimeWithConfig(RealtimeConfig{HeartbeatInterval: 5 * time.Second})
+	if rt.heartbeatInterval != 5*time.Second {
+		t.Errorf("configured heartbeat interval = %v, want 5s", rt.heartbeatInterval)
+	}
+	rt.SetHeartbeatInterval(0)
+	if rt.heartbeatInterval != 30*time.Second {
+		t.Errorf("SetHeartbeatInterval(0) left %v, want the 30s default", rt.heartbeatInterval)
+	}
+}
+
+func TestRealtimeHeartbeatReconnect(t *testing.T) {
+	rejoined := make(chan string, 1)
+	srv := fakeRealtimeServer(func(c *wsConn, n int, msg realtimeMessage) {
+		if msg.Event == "heartbeat" && n == 1 {
+			return // The first connection stops acknowledging heartbeats.
+		}
+		if msg.Event == "phx_join" && n == 2 {
+			rejoined <- msg.Topic
+		}
+		writeRealtime(c, msg.Topic, "phx_reply", msg.Ref, `{"status":"ok","response":{}}`)
+	})
+	defer srv.Close()
+	rt := NewClient(Config{BaseURL: srv.URL, APIKey: "anon"}).RealtimeWithConfig(RealtimeConfig{HeartbeatInterval: 20 * time.Millisecond})
+	var mu sync.Mutex
+	var statuses []ConnectionStatus
+	rt.OnStatusChange(func(s ConnectionStatus) {
+		mu.Lock()
+		statuses = append(statuses, s)
+		mu.Unlock()
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := rt.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer rt.Close()
+	ch := rt.Channel("todos")
+	if err := ch.Subscribe(ctx); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	select {
+	case topic := <-rejoined:
+		if topic != "realtime:todos" {
+			t.Errorf("rejoined %q, want realtime:todos", topic)
+		}
+	case <-ctx.Done():
+		t.Fatal("channel was not rejoined after missed heartbeats")
+	}
+	for ch.State() != ChannelJoined && ctx.Err() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	if rt.Status() != StatusOpen || ch.State() != ChannelJoined {
+		t.Errorf("after reconnect: status %v, channel %v", rt.Status(), ch.State())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	want := []ConnectionStatus{StatusConnecting, StatusOpen, StatusReconnecting, StatusOpen}
+	if fmt.Sprint(statuses) != fmt.Sprint(want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+}
+
+func TestListRecursive(t *testing.T) {
+	tree := map[string][]FileObject{
+		"docs":         {{Name: "2024/"}, {Name: "readme.md", ID: "1"}},
+		"docs/2024":    {{Name: "05"}, {Name: "summary.pdf", ID: "2"}},
+		"docs/2024/05": {{Name: "report.pdf", ID: "3"}},
+	}
+	for i := 0; i < storageListLimit; i++ {
+		tree["docs/2024/05"] = append(tree["docs/2024/05"], FileObject{Name: fmt.Sprintf("scan%03d.png", i), ID: "s"})
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != STORAGE_URL+"/object/list/files" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		var body struct {
+			Prefix string `json:"prefix"`
+			Limit  int    `json:"limit"`
+			Offset int    `json:"offset"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		entries := tree[body.Prefix]
+		if body.Offset > len(entries) {
+			body.Offset = len(entries)
+		}
+		entries = entries[body.Offset:]
+		if len(entries) > body.Limit {
+			entries = entries[:body.Limit]
+		}
+		json.NewEncoder(w).Encode(entries)
+	}))
+	defer srv.Close()
+	bucket := NewClient(Config{BaseURL: srv.URL, APIKey: "key"}).Storage().Bucket("files")
+
+	files, err := bucket.ListRecursive("/docs/", "")
+	if err != nil {
+		t.Fatalf("ListRecursive failed: %v", err)
+	}
+	if len(files) != 103 || files[0].Name != "2024/05/report.pdf" || files[101].Name != "2024/summary.pdf" || files[102].Name != "readme.md" {
+		t.Errorf("ListRecursive returned %d files: first %q, last %q", len(files), files[0].Name, files[len(files)-1].Name)
+	}
+}
+
+func TestTableJSONRoundTrip(t *testing.T) {
+	client := NewClient(Config{BaseURL: "https://example.supabase.co", APIKey: "anon"})
+	orig := client.Table("tenants").
+		Eq("plan", "pro").
+		Gte("max_users", 0).
+		Eq("deleted_at", nil).
+		In("id", []interface{}{"a", "b,c"}).
+		Or(Eq("region", "eu"), Not(Like("name", "test%"))).
+		Order(OrderOption{Field: "created_at", Direction: "desc", NullsFirst: true}).
+		Limit(10).Offset(20).
+		SelectColumns("id", "name")
+
+	data, err := orig.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	restored, err := client.FromJSON(data)
+	if err != nil {
+		t.Fatalf("FromJSON(%s) failed: %v", data, err)
+	}
+	if restored.String() != orig.String() {
+		t.Errorf("round trip changed the query:\n got %s\nwant %s", restored.String(), orig.String())
+	}
+
+	for _, bad := range []string{`{}`, `{"table":"t","filters":[{"op":"eq"}]}`, `{"table":"t","filters":[{"column":"a","op":"eq&x"}]}`} {
+		if _, err := client.FromJSON([]byte(bad)); err == nil {
+			t.Errorf("FromJSON(%s) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestGetUserFromToken(t *testing.T) {
+	token := func(claims string) string {
+		return "eyJhbGciOiJIUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".signature"
+	}
+	auth := NewClient(Config{}).Auth()
+
+	user, err := auth.GetUserFromToken(token(`{"sub":"u1","aud":"authenticated","role":"authenticated","email":"ada@example.com",` +
+		`"app_metadata":{"provider":"email"},"user_metadata":{"name":"Ada"},"exp":` + fmt.Sprint(time.Now().Add(time.Hour).Unix()) + `}`))
+	if err != nil || user.ID != "u1" || user.Aud != "authenticated" || user.Email != "ada@example.com" ||
+		user.AppMetadata["provider"] != "email" || user.UserMetadata["name"] != "Ada" {
+		t.Fatalf("GetUserFromToken = %+v, %v", user, err)
+	}
+	if user, err := auth.GetUserFromToken(token(`{"sub":"u2","aud":["authenticated"],"is_anonymous":true}`)); err != nil || user.Aud != "authenticated" || !user.IsAnonymous {
+		t.Errorf("GetUserFromToken with array aud = %+v, %v", user, err)
+	}
+	if _, err := auth.GetUserFromToken(token(`{"sub":"u1","exp":1}`)); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expired token: err = %v, want ErrUnauthorized", err)
+	}
+	if _, err := auth.GetUserFromToken("not-a-jwt"); err == nil {
+		t.Error("malformed token accepted")
+	}
+}
+
+func TestUploadLarge(t *testing.T) {
+	var (
+		stored  []byte
+		length  = int64(-1)
+		patches int
+		failAt  = 2 // Fail the third PATCH once
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Tus-Resumable") != "1.0.0" {
+			t.Errorf("%s %s without Tus-Resumable", r.Method, r.URL.Path)
+		}
+		switch r.Method {
+		case "POST":
+			if r.URL.Path != STORAGE_URL+"/upload/resumable" || !strings.Contains(r.Header.Get("Upload-Metadata"), "objectName "+base64.StdEncoding.EncodeToString([]byte("videos/intro.mp4"))) {
+				t.Errorf("create request = %s with metadata %q", r.URL.Path, r.Header.Get("Upload-Metadata"))
+			}
+			w.Header().Set("Location", STORAGE_URL+"/upload/resumable/abc")
+			w.WriteHeader(http.StatusCreated)
+		case "HEAD":
+			w.Header().Set("Upload-Offset", strconv.Itoa(len(stored)))
+		case "PATCH":
+			patches++
+			if patches == failAt+1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if r.Header.Get("Upload-Offset") != strconv.Itoa(len(stored)) {
+				t.Errorf("PATCH at offset %s, server has %d", r.Header.Get("Upload-Offset"), len(stored))
+			}
+			chunk, _ := io.ReadAll(r.Body)
+			stored = append(stored, chunk...)
+			if l := r.Header.Get("Upload-Length"); l != "" {
+				length, _ = strconv.ParseInt(l, 10, 64)
+			}
+			w.Header().Set("Upload-Offset", strconv.Itoa(len(stored)))
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+	bucket := NewClient(Config{BaseURL: srv.URL, APIKey: "key"}).Storage().Bucket("media")
+	content := "0123456789abcdefghij" // Five chunks of 4 bytes
+
+	var saved []byte
+	tracker := &PartialUploadTracker{OnChunkDone: func(tr *PartialUploadTracker, chunk int) {
+		saved, _ = json.Marshal(tr)
+	}}
+	if err := bucket.UploadLargeWithTracker("videos/intro.mp4", strings.NewReader(content), "video/mp4", 4, tracker, ""); err == nil {
+		t.Fatal("first attempt succeeded, want the injected failure")
+	}
+
+	var resumed PartialUploadTracker
+	if err := json.Unmarshal(saved, &resumed); err != nil || len(resumed.Completed) != 2 || !resumed.ChunkDone(1) || resumed.ChunkDone(2) {
+		t.Fatalf("saved tracker = %s (%v)", saved, err)
+	}
+	if err := bucket.UploadLargeWithTracker("videos/intro.mp4", strings.NewReader(content), "video/mp4", 4, &resumed, ""); err != nil {
+		t.Fatalf("resumed upload failed: %v", err)
+	}
+	if string(stored) != content || length != int64(len(content)) || len(resumed.Completed) != 5 {
+		t.Errorf("server has %q (length %d), tracker %v", stored, length, resumed.Completed)
+	}
+}
+
+func TestSelectIfModified(t *testing.T) {
+	version := `"v1"`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == version {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", version)
+		w.Write([]byte(`[{"id":"1","name":"Acme"}]`))
+	}))
+	defer srv.Close()
+	table := NewClient(Config{BaseURL: srv.URL, APIKey: "anon"}).Table("test_tenants")
+
+	var tenants []TestTenant
+	etag, modified, err := table.SelectIfModified(&tenants, "", "")
+	if err != nil || !modified || etag != `"v1"` || len(tenants) != 1 {
+		t.Fatalf("first SelectIfModified = %q, %v, %v (rows %v)", etag, modified, err, tenants)
+	}
+	var again []TestTenant
+	etag, modified, err = table.SelectIfModified(&again, etag, "")
+	if err != nil || modified || etag != `"v1"` || again != nil {
+		t.Errorf("unchanged SelectIfModified = %q, %v, %v (rows %v)", etag, modified, err, again)
+	}
+	version = `"v2"`
+	etag, modified, err = table.SelectIfModified(&again, etag, "")
+	if err != nil || !modified || etag != `"v2"` || len(again) != 1 {
+		t.Errorf("changed SelectIfModified = %q, %v, %v", etag, modified, err)
+	}
+}
+
+func TestFunctionsHooks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Request-ID") != "req-1" || r.Header.Get("X-Tenant") != "acme" {
+			t.Errorf("headers = %v", r.Header)
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+	fn := NewClient(Config{BaseURL: srv.URL, APIKey: "anon"}).Functions()
+	var calls []string
+	fn.BeforeInvoke(func(name string, req *http.Request) error {
+		calls = append(calls, "before1:"+name)
+		req.Header.Set("X-Request-ID", "req-1")
+		return nil
+	})
+	fn.BeforeInvoke(func(name string, req *http.Request) error {
+		calls = append(calls, "before2:"+name)
+		if name == "blocked" {
+			return errors.New("blocked by hook")
+		}
+		req.Header.Set("X-Tenant", "acme")
+		return nil
+	})
+	fn.AfterInvoke(func(name string, resp *http.Response, err error) {
+		if err != nil {
+			calls = append(calls, "after:"+name+":"+err.Error())
+			return
+		}
+		calls = append(calls, fmt.Sprintf("after:%s:%d", name, resp.StatusCode))
+	})
+
+	if _, err := fn.Invoke("hello", nil, ""); err != nil {
+		t.Fatalf("Invoke failed: %v", err)
+	}
+	if _, err := fn.Invoke("blocked", nil, ""); err == nil || err.Error() != "blocked by hook" {
+		t.Errorf("Invoke(blocked) = %v, want the hook's error", err)
+	}
+	want := []string{"before1:hello", "before2:hello", "after:hello:200", "before1:blocked", "before2:blocked", "after:blocked:blocked by hook"}
+	if strings.Join(calls, ",") != strings.Join(want, ",") {
+		t.Errorf("hook calls = %v, want %v", calls, want)
+	}
+}
+
+func TestRealtimeMaxReconnectAttempts(t *testing.T) {
+	var dials atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dials.Add(1)
+		http.Error(w, "project not found", http.StatusNotFound)
+	}))
+	defer srv.Close()
+	rt := NewClient(Config{BaseURL: srv.URL, APIKey: "anon"}).Realtime()
+	rt.SetMaxReconnectAttempts(3)
+	rt.reconnectBackoff = time.Millisecond
+	var gaveUp []error
+	rt.OnMaxRetriesExceeded(func(err error) { gaveUp = append(gaveUp, err) })
+
+	// Simulate a joined channel on a connection that has stopped answering heartbeats.
+	client, server := net.Pipe()
+	go io.Copy(io.Discard, server)
+	conn := &wsConn{conn: client, br: bufio.NewReader(client)}
+	done := make(chan struct{})
+	close(done)
+	rt.conn, rt.done = conn, done
+	ch := rt.Channel("todos")
+	ch.setState(ChannelJoined)
+
+	rt.reconnect(conn)
+	if n := dials.Load(); n != 3 {
+		t.Errorf("reconnect dialled %d times, want 3", n)
+	}
+	if len(gaveUp) != 1 || !strings.Contains(gaveUp[0].Error(), "after 3 attempts") {
+		t.Errorf("OnMaxRetriesExceeded calls = %v", gaveUp)
+	}
+	if rt.Status() != StatusClosed || rt.ChannelCount() != 0 || ch.State() != ChannelClosed {
+		t.Errorf("after giving up: status %v, %d channels, channel %v", rt.Status(), rt.ChannelCount(), ch.State())
+	}
+}
+
+func TestRealtimeLostConnectionGivesUp(t *testing.T) {
+	var dials atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if dials.Add(1) > 1 {
+			http.Error(w, "project not found", http.StatusNotFound)
+			return
+		}
+		// The first connection is dropped by the server once the channel has joined.
+		c := acceptWebSocket(w, r)
+		if c == nil {
+			return
+		}
+		defer c.conn.Close()
+		data, err := c.readMessage()
+		var msg realtimeMessage
+		if err != nil || json.Unmarshal(data, &msg) != nil {
+			return
+		}
+		writeRealtime(c, msg.Topic, "phx_reply", msg.Ref, `{"status":"ok","response":{}}`)
+	}))
+	defer srv.Close()
+	rt := NewClient(Config{BaseURL: srv.URL, APIKey: "anon"}).Realtime()
+	rt.SetMaxReconnectAttempts(3)
+	rt.reconnectBackoff = time.Millisecond
+	gaveUp := make(chan error, 1)
+	rt.OnMaxRetriesExceeded(func(err error) { gaveUp <- err })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := rt.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer rt.Close()
+	ch := rt.Channel("todos")
+	if err := ch.Subscribe(ctx); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	select {
+	case err := <-gaveUp:
+		if !strings.Contains(err.Error(), "after 3 attempts") {
+			t.Errorf("OnMaxRetriesExceeded(%v)", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("OnMaxRetriesExceeded not called after the connection was lost")
+	}
+	if n := dials.Load(); n != 4 {
+		t.Errorf("dialled %d times, want the first connection and 3 reconnect attempts", n)
+	}
+	if rt.Status() != StatusClosed || rt.ChannelCount() != 0 || ch.State() != ChannelClosed {
+		t.Errorf("after giving up: status %v, %d channels, channel %v", rt.Status(), rt.ChannelCount(), ch.State())
+	}
+}
+
+func TestReconnectWait(t *testing.T) {
+	cases := map[int]time.Duration{0: 0, 1: time.Second, 2: 2 * time.Second, 5: 16 * time.Second, 6: 30 * time.Second, 40: 30 * time.Second, 100: 30 * time.Second}
+	for attempt, want := range cases {
+		if got := reconnectWait(time.Second, attempt); got != want {
+			t.Errorf("reconnectWait(1s, %d) = %v, want %v", attempt, got, want)
+		}
+	}
+	if got := reconnectWait(time.Hour, 3); got != realtimeMaxReconnectBackoff {
+		t.Errorf("reconnectWait(1h, 3) = %v, want the cap", got)
+	}
+}
+
+func TestSelectLazy(t *testing.T) {
+	fake := NewFakeSupabaseHandler()
+	for i := 0; i < selectLazyPageSize+5; i++ {
+		fake.Seed("events", map[string]interface{}{"id": i})
+	}
+	client, cleanup := NewTestClient(fake)
+	defer cleanup()
+
+	var ids []float64
+	for res := range client.Table("events").SelectLazy("") {
+		if res.Err != nil {
+			t.Fatalf("SelectLazy error: %v", res.Err)
+		}
+		ids = append(ids, res.Row["id"].(float64))
+	}
+	if len(ids) != selectLazyPageSize+5 || ids[selectLazyPageSize] != selectLazyPageSize {
+		t.Errorf("SelectLazy returned %d rows", len(ids))
+	}
+
+	n := 0
+	for range client.Table("events").Offset(2).Limit(selectLazyPageSize + 1).SelectLazy("") {
+		n++
+	}
+	if n != selectLazyPageSize+1 {
+		t.Errorf("SelectLazy with Limit returned %d rows, want %d", n, selectLazyPageSize+1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := client.Table("events").SelectLazyCtx(ctx, "")
+	<-results
+	cancel()
+	for range results {
+		// Drains at most the row already being sent; the channel must then close.
+	}
+
+	// A server capping pages below selectLazyPageSize (PostgREST max-rows) must not truncate
+	// the result.
+	const maxRows, total = 3, 7
+	capped := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		rows := []map[string]int{}
+		for i := offset; i < total && len(rows) < maxRows; i++ {
+			rows = append(rows, map[string]int{"id": i})
+		}
+		json.NewEncoder(w).Encode(rows)
+	}))
+	defer capped.Close()
+	n = 0
+	for res := range NewClient(Config{BaseURL: capped.URL}).Table("events").SelectLazy("") {
+		if res.Err != nil || res.Row["id"] != float64(n) {
+			t.Fatalf("SelectLazy with max-rows: result %d = %+v", n, res)
+		}
+		n++
+	}
+	if n != total {
+		t.Errorf("SelectLazy with max-rows %d returned %d rows, want %d", maxRows, n, total)
+	}
+
+	for res := range client.Table("missing/table").SelectLazy("") {
+		if res.Err == nil {
+			t.Errorf("SelectLazy on an invalid path sent a row: %v", res.Row)
+		}
+	}
+}
+
+// acceptWebSocket completes the server side of a WebSocket handshake, or returns nil after
+// rejecting a request that is not one.
+func acceptWebSocket(w http.ResponseWriter, r *http.Request) *wsConn {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+		http.Error(w, "not a websocket handshake", http.StatusBadRequest)
+		return nil
+	}
+	conn, brw, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return nil
+	}
+	sum := sha1.Sum([]byte(key + wsGUID))
+	brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	brw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	brw.Flush()
+	return &wsConn{conn: conn, br: brw.Reader}
+}
+
+// writeServerFrame writes a frame as a server would, unmasked unless mask is given.
+func writeServerFrame(c *wsConn, fin bool, opcode byte, payload []byte, mask ...byte) error {
+	head := []byte{opcode}
+	if fin {
+		head[0] |= 0x80
+	}
+	var maskBit byte
+	if len(mask) == 4 {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		head = append(head, maskBit|byte(n))
+	case n <= 0xFFFF:
+		head = append(head, maskBit|126, byte(n>>8), byte(n))
+	default:
+		head = append(head, maskBit|127, 0, 0, 0, 0, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	if maskBit != 0 {
+		head = append(head, mask...)
+		masked := make([]byte, len(payload))
+		for i, b := range payload {
+			masked[i] = b ^ mask[i%4]
+		}
+		payload = masked
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := c.conn.Write(append(head, payload...))
+	return err
+}
+
+// writeRealtime sends a Phoenix message from the server.
+func writeRealtime(c *wsConn, topic, event, ref, payload string) error {
+	b, _ := json.Marshal(realtimeMessage{Topic: topic, Event: event, Ref: ref, Payload: json.RawMessage(payload)})
+	return writeServerFrame(c, true, wsText, b)
+}
+
+// fakeRealtimeServer is a Realtime server speaking just enough of the Phoenix protocol for
+// tests: handle is called with each message received on the nth connection (counting from 1)
+// and replies with writeRealtime. It must not use t, as it can outlive the test.
+func fakeRealtimeServer(handle func(c *wsConn, n int, msg realtimeMessage)) *httptest.Server {
+	var conns atomic.Int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := acceptWebSocket(w, r)
+		if c == nil {
+			return
+		}
+		defer c.conn.Close()
+		n := int(conns.Add(1))
+		for {
+			data, err := c.readMessage()
+			if err != nil {
+				return
+			}
+			var msg realtimeMessage
+			if json.Unmarshal(data, &msg) != nil {
+				return
+			}
+			handle(c, n, msg)
+		}
+	}))
+}
+
+func TestWebSocketFrames(t *testing.T) {
+	server := make(chan *wsConn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Connection") != "Upgrade" {
+			t.Errorf("Connection = %q", r.Header.Get("Connection"))
+		}
+		if c := acceptWebSocket(w, r); c != nil {
+			server <- c
+		}
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := dialWebSocket(ctx, "ws"+strings.TrimPrefix(srv.URL, "http")+"/socket")
+	if err != nil {
+		t.Fatalf("dialWebSocket: %v", err)
+	}
+	defer client.conn.Close()
+	sc := <-server
+	defer sc.conn.Close()
+
+	// Client frames must be masked.
+	if err := client.writeText([]byte("hello server")); err != nil {
+		t.Fatalf("writeText: %v", err)
+	}
+	if head, err := sc.br.Peek(2); err != nil || head[0] != 0x80|wsText || head[1]&0x80 == 0 {
+		t.Fatalf("client frame header = %x, %v; want a masked final text frame", head, err)
+	}
+	if msg, err := sc.readMessage(); err != nil || string(msg) != "hello server" {
+		t.Fatalf("server read %q, %v", msg, err)
+	}
+
+	// A fragmented message with a ping between the fragments is reassembled, and the ping is
+	// answered with a pong carrying the same payload.
+	writeServerFrame(sc, false, wsText, []byte("hel"))
+	writeServerFrame(sc, true, wsPing, []byte("p1"))
+	writeServerFrame(sc, false, wsContinuation, []byte("lo "), 1, 2, 3, 4)
+	writeServerFrame(sc, true, wsContinuation, []byte("client"))
+	if msg, err := client.readMessage(); err != nil || string(msg) != "hello client" {
+		t.Fatalf("client read %q, %v", msg, err)
+	}
+	if fin, opcode, payload, err := sc.readFrame(wsMaxMessage); err != nil || !fin || opcode != wsPong || string(payload) != "p1" {
+		t.Fatalf("server got %v %x %q, %v; want a pong", fin, opcode, payload, err)
+	}
+
+	// Fragments may not add up to more than wsMaxMessage.
+	big := make([]byte, wsMaxMessage/2+1)
+	go func() {
+		writeServerFrame(sc, false, wsText, big)
+		writeServerFrame(sc, true, wsContinuation, big)
+	}()
+	if _, err := client.readMessage(); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Fatalf("oversized fragmented message: err = %v", err)
+	}
+}
+
+func TestWebSocketHandshakeRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, brw, _ := w.(http.Hijacker).Hijack()
+		defer conn.Close()
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: bm90IHRoZSBrZXk=\r\n\r\n")
+		brw.Flush()
+	}))
+	defer srv.Close()
+	if _, err := dialWebSocket(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http")); err == nil {
+		t.Error("dialWebSocket accepted an invalid Sec-WebSocket-Accept")
+	}
+}
+
+func TestRealtimePostgresChanges(t *testing.T) {
+	joins := make(chan realtimeMessage, 1)
+	srv := fakeRealtimeServer(func(c *wsConn, n int, msg realtimeMessage) {
+		switch msg.Event {
+		case "phx_join":
+			joins <- msg
+			writeRealtime(c, msg.Topic, "phx_reply", msg.Ref, `{"status":"ok","response":{"postgres_changes":[{"id":7,"event":"INSERT","schema":"public","table":"todos"},{"id":8,"event":"DELETE","schema":"public","table":"todos"}]}}`)
+			writeRealtime(c, msg.Topic, "postgres_changes", "", `{"ids":[8],"data":{"schema":"public","table":"todos","type":"DELETE","old_record":{"id":1}}}`)
+			writeRealtime(c, msg.Topic, "postgres_changes", "", `{"ids":[7],"data":{"schema":"public","table":"todos","type":"INSERT","record":{"id":2}}}`)
+		default:
+			writeRealtime(c, msg.Topic, "phx_reply", msg.Ref, `{"status":"ok","response":{}}`)
+		}
+	})
+	defer srv.Close()
+	rt := NewClient(Config{BaseURL: srv.URL, APIKey: "anon"}).Realtime()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := rt.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer rt.Close()
+
+	inserts := make(chan RealtimePostgresChange, 2)
+	ch := rt.Channel("todos").OnPostgresChanges(PostgresChangesFilter{Event: "INSERT", Table: "todos"}, func(c RealtimePostgresChange) {
+		inserts <- c
+	})
+	if err := ch.Subscribe(ctx); err != nil || ch.State() != ChannelJoined {
+		t.Fatalf("Subscribe = %v, state %v", err, ch.State())
+	}
+	join := <-joins
+	var payload struct {
+		Config struct {
+			PostgresChanges []postgresChangesConfig `json:"postgres_changes"`
+		} `json:"config"`
+		AccessToken string `json:"access_token"`
+	}
+	json.Unmarshal(join.Payload, &payload)
+	if join.Topic != "realtime:todos" || payload.AccessToken != "anon" || len(payload.Config.PostgresChanges) != 1 || payload.Config.PostgresChanges[0].Table != "todos" {
+		t.Errorf("phx_join = %s", join.Payload)
+	}
+	select {
+	case c := <-inserts:
+		if c.EventType != "INSERT" || c.New["id"] != float64(2) {
+			t.Errorf("change = %+v, want the INSERT of row 2", c)
+		}
+	case <-ctx.Done():
+		t.Fatal("no postgres_changes event delivered")
+	}
+	if len(inserts) != 0 {
+		t.Errorf("handler got a change bound to another id: %+v", <-inserts)
+	}
+}
+
+func TestWatchTableConcurrentHandlers(t *testing.T) {
+	srv := fakeRealtimeServer(func(c *wsConn, n int, msg realtimeMessage) {
+		writeRealtime(c, msg.Topic, "phx_reply", msg.Ref, `{"status":"ok","response":{}}`)
+		if msg.Event == "phx_join" {
+			for id := 1; id <= 2; id++ {
+				writeRealtime(c, msg.Topic, "postgres_changes", "", fmt.Sprintf(`{"data":{"schema":"public","table":"todos","type":"INSERT","record":{"id":%d}}}`, id))
+			}
+		}
+	})
+	defer srv.Close()
+	client := NewClient(Config{BaseURL: srv.URL, APIKey: "anon"})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The handler for the first change waits for the second, which can only be delivered if
+	// each change gets its own goroutine.
+	second := make(chan struct{})
+	var finished atomic.Int32
+	err := client.WatchTable(ctx, "public", "todos", []string{"INSERT"}, func(c RealtimePostgresChange) {
+		defer finished.Add(1)
+		if c.New["id"] == float64(2) {
+			close(second)
+			cancel()
+			return
+		}
+		select {
+		case <-second:
+		case <-time.After(3 * time.Second):
+			t.Error("handlers were not run concurrently")
+		}
+	})
+	if err != nil {
+		t.Fatalf("WatchTable: %v", err)
+	}
+	if n := finished.Load(); n != 2 {
+		t.Errorf("WatchTable returned with %d of 2 handler calls finished", n)
+	}
+}
+
+func TestWatchTableReconnect(t *testing.T) {
+	srv := fakeRealtimeServer(func(c *wsConn, n int, msg realtimeMessage) {
+		if msg.Event == "heartbeat" && n == 1 {
+			return // The first connection stops acknowledging heartbeats.
+		}
+		writeRealtime(c, msg.Topic, "phx_reply", msg.Ref, `{"status":"ok","response":{}}`)
+		if msg.Event == "phx_join" {
+			writeRealtime(c, msg.Topic, "postgres_changes", "", fmt.Sprintf(`{"data":{"schema":"public","table":"todos","type":"INSERT","record":{"id":%d}}}`, n))
+		}
+	})
+	defer srv.Close()
+	rt := NewClient(Config{BaseURL: srv.URL, APIKey: "anon"}).RealtimeWithConfig(RealtimeConfig{HeartbeatInterval: 20 * time.Millisecond})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	var ids []interface{}
+	err := rt.watchTable(ctx, "public", "todos", []string{"INSERT"}, func(c RealtimePostgresChange) {
+		mu.Lock()
+		defer mu.Unlock()
+		ids = append(ids, c.New["id"])
+		if c.New["id"] == float64(2) {
+			cancel() // Delivered on the second connection
+		}
+	})
+	if err != nil {
+		t.Fatalf("WatchTable: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(ids) != "[1 2]" {
+		t.Errorf("changes from connections %v, want [1 2]", ids)
+	}
+}
+
+func TestReadSSEEvents(t *testing.T) {
+	stream := ": keep-alive\r\n" +
+		"id: 1\nevent: progress\ndata: {\"step\":1}\n\n" +
+		"event: ignored\n\n" + // No data lines, so nothing is dispatched
+		"data: line one\ndata:line two\nretry: 3000\n\n" +
+		"data\n\n" +
+		"data: unterminated"
+	var got []SSEEvent
+	for ev := range ReadSSEEvents(strings.NewReader(stream)) {
+		got = append(got, ev)
+	}
+	want := []SSEEvent{
+		{ID: "1", Event: "progress", Data: `{"step":1}`},
+		{Data: "line one\nline two", Retry: 3000},
+		{Data: ""},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("events = %+v, want %+v", got, want)
+	}
+
+	// A line too long to buffer ends the stream with an error event.
+	got = nil
+	long := "data: " + strings.Repeat("x", 2<<20) + "\n\n"
+	for ev := range ReadSSEEvents(strings.NewReader("data: ok\n\n" + long)) {
+		got = append(got, ev)
+	}
+	if len(got) != 2 || got[0].Data != "ok" || got[1].Err == nil {
+		t.Errorf("events with an oversized line = %+v, want one event then an error", got)
+	}
+
+	// Cancelling ctx closes the channel even though the reader has more events.
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	go func() {
+		for {
+			if _, err := pw.Write([]byte("data: tick\n\n")); err != nil {
+				return
+			}
+		}
+	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	events := ReadSSEEventsCtx(ctx, pr)
+	<-events
+	cancel()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if ok {
+				continue
+			}
+		case <-timeout:
+			t.Fatal("ReadSSEEventsCtx did not stop after ctx was cancelled")
+		}
+		break
+	}
+
+	// Without a context, closing the reader stops it.
+	pr2, pw2 := io.Pipe()
+	go func() {
+		for {
+			if _, err := pw2.Write([]byte("data: tick\n\n")); err != nil {
+				return
+			}
+		}
+	}()
+	events = ReadSSEEvents(pr2)
+	<-events
+	pr2.Close()
+	timeout = time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if ok {
+				continue
+			}
+		case <-timeout:
+			t.Fatal("ReadSSEEvents did not stop after the reader was closed")
+		}
+		break
+	}
+}
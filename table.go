@@ -2,11 +2,14 @@ package supabasego
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"reflect"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -20,6 +23,19 @@ type Table struct {
 	limit      int
 	offset     int
 	selectCols []string
+	timeout    time.Duration
+	// defaultFilters is the number of leading entries in filters that were set by
+	// TableWithDefaults; Reset keeps them.
+	defaultFilters int
+	strictParsing  bool
+	returnMinimal  bool
+	primaryKey     string
+	single         bool
+	beforeInsert   func(record interface{}) error
+	beforeUpdate   func(values map[string]interface{}) error
+	// pageErr is set by Page when called with an invalid page size; reads fail with it until
+	// Reset is called. See queryErr.
+	pageErr error
 }
 
 // Filter interface and types
@@ -34,28 +50,46 @@ type simpleFilter struct {
 }
 
 func (f simpleFilter) toQuery() string {
-	if f.value == nil {
-		return fmt.Sprintf("%s.is.null", f.field)
+	cond, ok := f.condition()
+	if !ok {
+		return ""
 	}
-	// If value is a pointer and nil, treat as is.null
-	switch v := f.value.(type) {
-	case *string:
-		if v == nil {
-			return fmt.Sprintf("%s.is.null", f.field)
-		}
-	case *int:
-		if v == nil {
-			return fmt.Sprintf("%s.is.null", f.field)
-		}
-	case *time.Time:
-		if v == nil {
-			return fmt.Sprintf("%s.is.null", f.field)
+	return f.field + "." + cond
+}
+
+// condition returns the operator and value as sent after the column name, e.g. "gte.18". A nil
+// value or nil pointer is treated as NULL: eq and is become "is.null" and neq "not.is.null".
+// Other operators cannot compare against NULL, so ok is false and reads leave the filter out
+// (updates and deletes reject it, see checkNilFilter).
+func (f simpleFilter) condition() (cond string, ok bool) {
+	if isNilValue(f.value) {
+		switch f.op {
+		case "eq", "is":
+			return "is.null", true
+		case "neq":
+			return "not.is.null", true
 		}
+		return "", false
+	}
+	return fmt.Sprintf("%s.%v", f.op, f.value), true
+}
+
+// negate returns the negation of a condition, e.g. "not.like.test*" for "like.test*". PostgREST
+// accepts a single not, so negating "not.is.null" gives "is.null".
+func negate(cond string) string {
+	if rest, ok := strings.CutPrefix(cond, "not."); ok {
+		return rest
 	}
-	if f.op == "in" {
-		return fmt.Sprintf("%s.in.%v", f.field, f.value)
+	return "not." + cond
+}
+
+// isNilValue reports whether v is nil or a nil pointer.
+func isNilValue(v interface{}) bool {
+	if v == nil {
+		return true
 	}
-	return fmt.Sprintf("%s.%s.%v", f.field, f.op, f.value)
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
 }
 
 type groupFilter struct {
@@ -64,11 +98,27 @@ type groupFilter struct {
 }
 
 func (g groupFilter) toQuery() string {
+	value := g.paramValue()
+	if value == "" {
+		return ""
+	}
+	return g.operator + value
+}
+
+// paramValue returns the group's conditions in the form PostgREST expects as the value of a
+// top-level and/or query param, e.g. "(plan.eq.pro,max_users.gt.5)" for or=(...). Conditions
+// left out (see simpleFilter.condition) are skipped, and a group left empty returns "".
+func (g groupFilter) paramValue() string {
 	var parts []string
 	for _, f := range g.filters {
-		parts = append(parts, f.toQuery())
+		if q := f.toQuery(); q != "" {
+			parts = append(parts, q)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
 	}
-	return fmt.Sprintf("%s(%s)", g.operator, strings.Join(parts, ","))
+	return "(" + strings.Join(parts, ",") + ")"
 }
 
 // Filter constructors
@@ -105,12 +155,14 @@ func In(field string, values []interface{}) Filter {
 			continue
 		}
 		switch vv := v.(type) {
+		case string:
+			strVals = append(strVals, quoteListValue(vv))
 		case *string:
 			if vv == nil {
 				strVals = append(strVals, "null")
 				continue
 			}
-			strVals = append(strVals, *vv)
+			strVals = append(strVals, quoteListValue(*vv))
 		case *int:
 			if vv == nil {
 				strVals = append(strVals, "null")
@@ -127,8 +179,87 @@ func In(field string, values []interface{}) Filter {
 			strVals = append(strVals, fmt.Sprintf("%v", v))
 		}
 	}
-	joined := strings.Join(strVals, ",")
-	return simpleFilter{field, "in", fmt.Sprintf("(%s)", joined)}
+	return inList(field, strVals)
+}
+
+// InInt64s is In for a slice of int64 values.
+func InInt64s(field string, values []int64) Filter {
+	strVals := make([]string, len(values))
+	for i, v := range values {
+		strVals[i] = strconv.FormatInt(v, 10)
+	}
+	return inList(field, strVals)
+}
+
+// InFloat64s is In for a slice of float64 values.
+func InFloat64s(field string, values []float64) Filter {
+	strVals := make([]string, len(values))
+	for i, v := range values {
+		strVals[i] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return inList(field, strVals)
+}
+
+// InUUIDs is In for a slice of UUID strings. Each value is validated: if one is not a UUID,
+// a query using the filter fails with an error naming it when run, so that bad input, e.g. from
+// a request, is reported rather than sent.
+func InUUIDs(field string, values []string) Filter {
+	for _, v := range values {
+		if !isUUID(v) {
+			return invalidFilter{fmt.Errorf("supabase: invalid UUID %q in filter on %s", v, field)}
+		}
+	}
+	return inList(field, values)
+}
+
+// InTimes is In for a slice of times, formatted as RFC 3339.
+func InTimes(field string, values []time.Time) Filter {
+	strVals := make([]string, len(values))
+	for i, v := range values {
+		strVals[i] = quoteListValue(v.Format(time.RFC3339Nano))
+	}
+	return inList(field, strVals)
+}
+
+// invalidFilter is a filter that could not be built. It is never sent: queries using it fail
+// with err when run.
+type invalidFilter struct {
+	err error
+}
+
+func (f invalidFilter) toQuery() string { return "" }
+
+func inList(field string, values []string) Filter {
+	return simpleFilter{field, "in", "(" + strings.Join(values, ",") + ")"}
+}
+
+// quoteListValue double-quotes a string for a PostgREST list when it contains characters that
+// PostgREST reserves (commas, dots, colons, parentheses, quotes, backslashes or spaces), so
+// "O'Reilly, Tim" stays one value instead of being split in two.
+func quoteListValue(s string) string {
+	if !strings.ContainsAny(s, ",.:()\" \\") {
+		return s
+	}
+	return pgrstQuote(s)
+}
+
+// pgrstQuote wraps s in double quotes, escaping backslashes and double quotes.
+func pgrstQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// InAny matches rows whose array column contains value as one of its elements, using the
+// contains operator with a single-element set: field=cs.{"value"}. Unlike testing equality
+// against the whole array, the column may hold any number of other elements.
+func InAny(field string, value interface{}) Filter {
+	var elem string
+	switch v := value.(type) {
+	case string:
+		elem = pgrstQuote(v)
+	default:
+		elem = fmt.Sprintf("%v", v)
+	}
+	return simpleFilter{field, "cs", "{" + elem + "}"}
 }
 func And(filters ...Filter) Filter {
 	return groupFilter{"and", filters}
@@ -137,10 +268,114 @@ func Or(filters ...Filter) Filter {
 	return groupFilter{"or", filters}
 }
 
+type notFilter struct {
+	filter Filter
+}
+
+func (n notFilter) toQuery() string {
+	switch f := n.filter.(type) {
+	case simpleFilter:
+		cond, ok := f.condition()
+		if !ok {
+			return ""
+		}
+		return f.field + "." + negate(cond)
+	case notFilter:
+		return f.filter.toQuery() // Double negation cancels out
+	}
+	q := n.filter.toQuery()
+	if q == "" {
+		return ""
+	}
+	return "not." + q
+}
+
+// Not negates a filter, e.g. Not(Like("name", "test%")) or Not(Or(...)).
+func Not(f Filter) Filter {
+	return notFilter{f}
+}
+
+// FilterGroup builds a nested filter tree step by step. Terms added with And, Or and Not
+// are combined with AND when Build is called.
+//
+//	f := NewFilterGroup().
+//		Or(Eq("plan", "pro"), Gt("max_users", 5)).
+//		Not(Like("name", "test%")).
+//		Build()
+type FilterGroup struct {
+	terms []Filter
+}
+
+// NewFilterGroup returns an empty FilterGroup.
+func NewFilterGroup() *FilterGroup {
+	return &FilterGroup{}
+}
+
+// And adds each filter as a term of the group.
+func (g *FilterGroup) And(filters ...Filter) *FilterGroup {
+	g.terms = append(g.terms, filters...)
+	return g
+}
+
+// Or adds a term that matches when any of the filters match.
+func (g *FilterGroup) Or(filters ...Filter) *FilterGroup {
+	g.terms = append(g.terms, Or(filters...))
+	return g
+}
+
+// Not adds a term that matches when f does not.
+func (g *FilterGroup) Not(f Filter) *FilterGroup {
+	g.terms = append(g.terms, Not(f))
+	return g
+}
+
+// Build returns the accumulated filter tree, or nil if the group is empty.
+func (g *FilterGroup) Build() Filter {
+	switch len(g.terms) {
+	case 0:
+		return nil
+	case 1:
+		return g.terms[0]
+	}
+	return And(g.terms...)
+}
+
 // filter, order, and other query option types will be defined here.
 type order struct {
-	field     string
-	direction string // "asc" or "desc"
+	field        string
+	direction    string // "asc" or "desc"
+	nullsFirst   bool
+	nullsLast    bool // Only set when reversing a nullsFirst order
+	foreignTable string
+}
+
+func (o order) toQuery() string {
+	q := fmt.Sprintf("%s.%s", o.field, o.direction)
+	if o.nullsFirst {
+		q += ".nullsfirst"
+	} else if o.nullsLast {
+		q += ".nullslast"
+	}
+	return q
+}
+
+// reversed returns the order that sorts rows the opposite way, NULLs included.
+func (o order) reversed() order {
+	r := o
+	r.direction = "desc"
+	if o.direction == "desc" {
+		r.direction = "asc"
+	}
+	r.nullsFirst, r.nullsLast = o.nullsLast, o.nullsFirst
+	return r
+}
+
+// OrderOption describes one ordering term for Order.
+type OrderOption struct {
+	Field        string `json:"field"`
+	Direction    string `json:"direction,omitempty"`     // "asc" (default) or "desc"
+	NullsFirst   bool   `json:"nulls_first,omitempty"`   // Sort NULLs before other values; otherwise the database default applies
+	ForeignTable string `json:"foreign_table,omitempty"` // Order rows of an embedded resource instead of the top-level rows
 }
 
 // Table returns a Table instance for the given table name.
@@ -151,12 +386,148 @@ func (c *Client) Table(name string) *Table {
 	}
 }
 
+// TableOptions configures a Table created with TableWithOptions.
+type TableOptions struct {
+	// PrimaryKey is the table's key column(s), comma-separated if composite. Upsert uses it as
+	// the default conflict target, and First and Last order by it when no order is set.
+	// Defaults to "id" for First and Last.
+	PrimaryKey string
+}
+
+// TableWithOptions returns a Table for the given table name configured with opts.
+func (c *Client) TableWithOptions(name string, opts TableOptions) *Table {
+	t := c.Table(name)
+	t.primaryKey = opts.PrimaryKey
+	return t
+}
+
+// TableWithDefaults returns a Table whose queries always include the given filters, e.g. a
+// tenant_id filter for multi-tenant isolation. The defaults survive Clone and Reset.
+func (c *Client) TableWithDefaults(name string, defaults ...Filter) *Table {
+	t := c.Table(name)
+	t.filters = append(t.filters, defaults...)
+	t.defaultFilters = len(defaults)
+	return t
+}
+
+// Clone returns an independent copy of the table and its query state.
+func (t *Table) Clone() *Table {
+	c := *t
+	c.filters = append([]Filter(nil), t.filters...)
+	c.orders = append([]order(nil), t.orders...)
+	c.selectCols = append([]string(nil), t.selectCols...)
+	return &c
+}
+
+// Reset clears filters, ordering, pagination, column selection and Single mode so the table can
+// be reused. Default filters set by TableWithDefaults are kept.
+func (t *Table) Reset() *Table {
+	t.filters = t.filters[:t.defaultFilters:t.defaultFilters]
+	t.orders = nil
+	t.limit = 0
+	t.offset = 0
+	t.selectCols = nil
+	t.single = false
+	t.pageErr = nil
+	return t
+}
+
+// ApplyFrom replaces dst's query state (filters, ordering, limit, offset and selected columns)
+// with a copy of src's, keeping dst's table name, client and settings. This lets a base query,
+// such as a tenant filter, be applied to several tables. Default filters of dst set by
+// TableWithDefaults are kept, ahead of src's filters.
+func (dst *Table) ApplyFrom(src *Table) *Table {
+	dst.filters = append(dst.filters[:dst.defaultFilters:dst.defaultFilters], src.filters...)
+	dst.orders = append([]order(nil), src.orders...)
+	dst.limit = src.limit
+	dst.offset = src.offset
+	dst.selectCols = append([]string(nil), src.selectCols...)
+	dst.pageErr = src.pageErr
+	return dst
+}
+
 // AddFilter allows adding a filter (for AND/OR/nested support)
 func (t *Table) AddFilter(f Filter) *Table {
 	t.filters = append(t.filters, f)
 	return t
 }
 
+// MatchStruct adds a filter for each non-zero field of the struct v (or pointer to one), to find
+// rows "by example". The column and operator come from a db:"column,op" tag, e.g.
+// db:"age,gte"; without one the json tag's name is used with eq, and untagged fields use the
+// field name. op may be eq, neq, gt, gte, lt, lte, like, ilike or in (for slice fields). Zero
+// values (empty strings, 0, false, nil) and fields tagged "-" are skipped.
+//
+//	client.Table("users").MatchStruct(struct {
+//		Plan   string `db:"plan"`
+//		MinAge int    `db:"age,gte"`
+//	}{Plan: "pro", MinAge: 18})
+func (t *Table) MatchStruct(v interface{}) *Table {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return t
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return t
+	}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		fv := rv.Field(i)
+		if !sf.IsExported() || fv.IsZero() {
+			continue
+		}
+		column, op := sf.Name, "eq"
+		if tag, ok := sf.Tag.Lookup("db"); ok {
+			name, tagOp, _ := strings.Cut(tag, ",")
+			if name == "-" {
+				continue
+			}
+			if name != "" {
+				column = name
+			}
+			if tagOp != "" {
+				op = tagOp
+			}
+		} else if tag, ok := sf.Tag.Lookup("json"); ok {
+			name, _, _ := strings.Cut(tag, ",")
+			if name == "-" {
+				continue
+			}
+			if name != "" {
+				column = name
+			}
+		} else if sf.Anonymous && fv.Kind() == reflect.Struct {
+			t.MatchStruct(fv.Interface())
+			continue
+		}
+		for fv.Kind() == reflect.Ptr {
+			fv = fv.Elem()
+		}
+		t.AddFilter(matchFilter(column, op, fv))
+	}
+	return t
+}
+
+// matchFilter builds the filter for one MatchStruct field.
+func matchFilter(column, op string, v reflect.Value) Filter {
+	if op == "in" && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) {
+		values := make([]interface{}, v.Len())
+		for i := range values {
+			values[i] = v.Index(i).Interface()
+		}
+		return In(column, values)
+	}
+	value := v.Interface()
+	if tm, ok := value.(time.Time); ok {
+		value = tm.Format(time.RFC3339Nano)
+	}
+	return simpleFilter{column, op, value}
+}
+
 // Keep Eq, Gt, etc. for backward compatibility
 func (t *Table) Eq(field string, value interface{}) *Table { return t.AddFilter(Eq(field, value)) }
 func (t *Table) NotEq(field string, value interface{}) *Table {
@@ -169,10 +540,14 @@ func (t *Table) Lte(field string, value interface{}) *Table   { return t.AddFilt
 func (t *Table) Like(field string, pattern string) *Table     { return t.AddFilter(Like(field, pattern)) }
 func (t *Table) ILike(field string, pattern string) *Table    { return t.AddFilter(ILike(field, pattern)) }
 func (t *Table) In(field string, values []interface{}) *Table { return t.AddFilter(In(field, values)) }
+func (t *Table) InAny(field string, value interface{}) *Table {
+	return t.AddFilter(InAny(field, value))
+}
 
-// And/Or as chainable methods
+// And/Or/Not as chainable methods
 func (t *Table) And(filters ...Filter) *Table { return t.AddFilter(And(filters...)) }
 func (t *Table) Or(filters ...Filter) *Table  { return t.AddFilter(Or(filters...)) }
+func (t *Table) Not(f Filter) *Table          { return t.AddFilter(Not(f)) }
 
 // Limit sets the maximum number of records to return.
 func (t *Table) Limit(n int) *Table {
@@ -181,41 +556,250 @@ func (t *Table) Limit(n int) *Table {
 }
 
 // OrderBy adds an order clause to the query (direction should be "asc" or "desc").
+//
+// Deprecated: Use Order, which also supports nulls ordering and foreign tables.
 func (t *Table) OrderBy(field, direction string) *Table {
-	dir := strings.ToLower(direction)
+	return t.Order(OrderOption{Field: field, Direction: direction})
+}
+
+// Order adds one or more order clauses to the query.
+func (t *Table) Order(opts ...OrderOption) *Table {
+	for _, o := range opts {
+		t.orders = append(t.orders, o.order())
+	}
+	return t
+}
+
+// order converts the option to an order clause; a direction other than asc or desc (in any
+// case) means asc.
+func (o OrderOption) order() order {
+	dir := strings.ToLower(o.Direction)
 	if dir != "asc" && dir != "desc" {
 		dir = "asc"
 	}
-	t.orders = append(t.orders, order{field: field, direction: dir})
+	return order{
+		field:        o.Field,
+		direction:    dir,
+		nullsFirst:   o.NullsFirst,
+		foreignTable: o.ForeignTable,
+	}
+}
+
+// Timeout sets a deadline for each request made through this Table, overriding Config.Timeout.
+// It is a simpler alternative to passing a context.
+func (t *Table) Timeout(d time.Duration) *Table {
+	t.timeout = d
+	return t
+}
+
+// ReturnMinimal makes Insert and InsertMaps ask PostgREST not to send the inserted rows back
+// (Prefer: return=minimal). This saves bandwidth on large imports; the records passed in are
+// then left unchanged.
+func (t *Table) ReturnMinimal() *Table {
+	t.returnMinimal = true
+	return t
+}
+
+// BeforeInsert sets a hook that Insert, InsertMaps and Upsert call with the record(s) before
+// sending them, e.g. to validate fields or fill in defaults. The hook may modify the record; if
+// it returns an error the write is not sent and that error is returned. The hook is kept by
+// Clone and Reset.
+func (t *Table) BeforeInsert(hook func(record interface{}) error) *Table {
+	t.beforeInsert = hook
 	return t
 }
 
+// BeforeUpdate sets a hook that Update calls with the values before sending them, e.g. to
+// validate fields or add an updated_by column. If it returns an error the update is not sent
+// and that error is returned. The hook is kept by Clone and Reset.
+func (t *Table) BeforeUpdate(hook func(values map[string]interface{}) error) *Table {
+	t.beforeUpdate = hook
+	return t
+}
+
+// returnPreference returns the Prefer header value for inserts.
+func (t *Table) returnPreference() string {
+	if t.returnMinimal {
+		return "return=minimal"
+	}
+	return "return=representation"
+}
+
+// httpClient returns the HTTP client for this table's requests: the shared client, or a
+// copy with the per-table timeout so the shared client is never mutated.
+func (t *Table) httpClient() *http.Client {
+	if t.timeout <= 0 {
+		return t.client.HTTPClient
+	}
+	hc := *t.client.HTTPClient
+	hc.Timeout = t.timeout
+	return &hc
+}
+
 // Offset sets the number of records to skip.
 func (t *Table) Offset(n int) *Table {
 	t.offset = n
 	return t
 }
 
+// Page selects page pageNumber (1-based) of pageSize rows, setting Offset((pageNumber-1)*pageSize)
+// and Limit(pageSize). A pageNumber below 1 is treated as 1 and logs a warning to Config.Logger.
+// A pageSize below 1 is rejected: the table is left unpaginated and reads fail with an error
+// until Reset is called.
+func (t *Table) Page(pageNumber, pageSize int) *Table {
+	if pageSize < 1 {
+		t.pageErr = fmt.Errorf("supabase: page size must be at least 1, got %d", pageSize)
+		return t
+	}
+	if pageNumber < 1 {
+		t.client.logger().Warn("supabase: page number must be at least 1; using page 1", "table", t.tableName, "page", pageNumber)
+		pageNumber = 1
+	}
+	return t.Offset((pageNumber - 1) * pageSize).Limit(pageSize)
+}
+
 // SelectColumns sets the columns to fetch.
 func (t *Table) SelectColumns(cols ...string) *Table {
 	t.selectCols = cols
 	return t
 }
 
-// Select fetches records from the table into dest (must be a pointer to a slice).
-func (t *Table) Select(dest interface{}, jwtToken string) error {
+// filterParams encodes the table's filters as PostgREST query parameters.
+func (t *Table) filterParams() url.Values {
 	params := url.Values{}
 	for _, f := range t.filters {
-		switch filter := f.(type) {
+		addFilterParam(params, f)
+	}
+	return params
+}
+
+// mutationFilterParams is filterParams for Update and Delete. A filter comparing against nil
+// with an operator other than eq, is or neq cannot be sent; a read just leaves it out, but
+// leaving it out of an update or delete would change more rows, so it is an error here.
+func (t *Table) mutationFilterParams() (url.Values, error) {
+	if err := t.filtersErr(); err != nil {
+		return nil, err
+	}
+	for _, f := range t.filters {
+		if err := checkNilFilter(f); err != nil {
+			return nil, err
+		}
+	}
+	return t.filterParams(), nil
+}
+
+// queryErr returns the error a read fails with before any request is made: an invalid page
+// size given to Page, or a filter that could not be built.
+func (t *Table) queryErr() error {
+	if t.pageErr != nil {
+		return t.pageErr
+	}
+	return t.filtersErr()
+}
+
+// filtersErr returns the error of the first filter, or filter nested in one, that could not be
+// built, e.g. InUUIDs given a value that is not a UUID.
+func (t *Table) filtersErr() error {
+	for _, f := range t.filters {
+		if err := filterErr(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func filterErr(f Filter) error {
+	switch filter := f.(type) {
+	case invalidFilter:
+		return filter.err
+	case groupFilter:
+		for _, sub := range filter.filters {
+			if err := filterErr(sub); err != nil {
+				return err
+			}
+		}
+	case notFilter:
+		return filterErr(filter.filter)
+	}
+	return nil
+}
+
+// checkNilFilter returns an error if f, or any filter nested in it, compares against nil with
+// an operator that has no NULL equivalent.
+func checkNilFilter(f Filter) error {
+	switch filter := f.(type) {
+	case simpleFilter:
+		if isNilValue(filter.value) && filter.op != "eq" && filter.op != "is" && filter.op != "neq" {
+			return fmt.Errorf("supabase: cannot filter %s with %s against nil", filter.field, filter.op)
+		}
+	case groupFilter:
+		for _, sub := range filter.filters {
+			if err := checkNilFilter(sub); err != nil {
+				return err
+			}
+		}
+	case notFilter:
+		return checkNilFilter(filter.filter)
+	}
+	return nil
+}
+
+// addFilterParam adds f to params as a PostgREST query parameter.
+func addFilterParam(params url.Values, f Filter) {
+	// Never send "<nil>": filters that cannot compare against NULL are left out.
+	switch filter := f.(type) {
+	case simpleFilter:
+		if cond, ok := filter.condition(); ok {
+			params.Add(filter.field, cond)
+		}
+	case groupFilter:
+		if value := filter.paramValue(); value != "" {
+			params.Add(filter.operator, value)
+		}
+	case notFilter:
+		switch inner := filter.filter.(type) {
 		case simpleFilter:
-			if filter.value == nil {
-				continue //
+			if cond, ok := inner.condition(); ok {
+				params.Add(inner.field, negate(cond))
 			}
-			params.Add(filter.field, fmt.Sprintf("%s.%v", filter.op, filter.value))
 		case groupFilter:
-			params.Add(filter.operator, filter.toQuery()[len(filter.operator)+1:]) // remove operator prefix
+			if value := inner.paramValue(); value != "" {
+				params.Add("not."+inner.operator, value)
+			}
+		case notFilter:
+			addFilterParam(params, inner.filter) // Double negation cancels out
 		}
 	}
+}
+
+// filterString renders f as the query parameter it is sent as, e.g. "age=gte.18" or
+// "or=(plan.eq.pro,max_users.gt.5)", without URL encoding.
+func filterString(f Filter) string {
+	params := url.Values{}
+	addFilterParam(params, f)
+	for key, values := range params {
+		return key + "=" + values[0]
+	}
+	return ""
+}
+
+// String returns the filter as a PostgREST query parameter, e.g. "age=gte.18".
+func (f simpleFilter) String() string { return filterString(f) }
+
+// String returns the group as a PostgREST query parameter, e.g. "and=(name.eq.Alice,age.gte.18)".
+func (g groupFilter) String() string { return filterString(g) }
+
+// String returns the negated filter as a PostgREST query parameter, e.g. "name=not.like.test*".
+func (n notFilter) String() string { return filterString(n) }
+
+// String returns the URL that Select would request, for debugging.
+func (t *Table) String() string {
+	return t.client.BaseURL + REST_URL + "/" + t.tableName + "?" + t.selectParams().Encode()
+}
+
+// selectParams encodes filters, pagination, ordering and column selection for a read query.
+func (t *Table) selectParams() url.Values {
+	params := t.filterParams()
 	if t.limit > 0 {
 		params.Add("limit", fmt.Sprintf("%d", t.limit))
 	}
@@ -223,17 +807,101 @@ func (t *Table) Select(dest interface{}, jwtToken string) error {
 		params.Add("offset", fmt.Sprintf("%d", t.offset))
 	}
 	if len(t.orders) > 0 {
-		var orderParams []string
+		// Orders on embedded resources go in a separate "<foreign_table>.order" param.
+		var keys []string
+		orderParams := map[string][]string{}
 		for _, o := range t.orders {
-			orderParams = append(orderParams, fmt.Sprintf("%s.%s", o.field, o.direction))
+			key := "order"
+			if o.foreignTable != "" {
+				key = o.foreignTable + ".order"
+			}
+			if _, ok := orderParams[key]; !ok {
+				keys = append(keys, key)
+			}
+			orderParams[key] = append(orderParams[key], o.toQuery())
+		}
+		for _, key := range keys {
+			params.Add(key, strings.Join(orderParams[key], ","))
 		}
-		params.Add("order", strings.Join(orderParams, ","))
 	}
 	if len(t.selectCols) > 0 {
 		params.Add("select", strings.Join(t.selectCols, ","))
 	} else {
 		params.Add("select", "*")
 	}
+	return params
+}
+
+// Single makes Select ask PostgREST for exactly one row as a JSON object, so dest must be a
+// pointer to a struct or map. The server rejects the query with ErrNoRows or ErrMultipleRows
+// unless exactly one row matches.
+func (t *Table) Single() *Table {
+	t.single = true
+	return t
+}
+
+// Select fetches records from the table into dest (must be a pointer to a slice, or to a single
+// struct or map in Single mode).
+func (t *Table) Select(dest interface{}, jwtToken string) error {
+	_, err := t.SelectWithHeaders(dest, jwtToken)
+	return err
+}
+
+// SelectWithHeaders is like Select but also returns the response headers, e.g. Cache-Control,
+// ETag, Content-Range or custom headers added by the server. Headers are also returned with an
+// error response.
+func (t *Table) SelectWithHeaders(dest interface{}, jwtToken string) (http.Header, error) {
+	req, err := t.selectRequest(jwtToken)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.client.doWith(t.httpClient(), req)
+	if err != nil {
+		return nil, fmt.Errorf("select request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return resp.Header, responseError("select", resp)
+	}
+	return resp.Header, json.NewDecoder(resp.Body).Decode(dest)
+}
+
+// SelectIfModified is a conditional Select for client-side caching. Pass the ETag returned by
+// the previous call (or "" the first time): if the rows have not changed since, the server
+// answers 304 Not Modified, dest is left untouched, and modified is false. Otherwise the rows
+// are decoded into dest and the response's ETag is returned with modified true. ETags are only
+// returned when the server or a proxy in front of it provides them.
+func (t *Table) SelectIfModified(dest interface{}, etag string, jwtToken string) (newETag string, modified bool, err error) {
+	req, err := t.selectRequest(jwtToken)
+	if err != nil {
+		return "", false, err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := t.client.doWith(t.httpClient(), req)
+	if err != nil {
+		return "", false, fmt.Errorf("select request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return etag, false, nil
+	}
+	if resp.StatusCode >= 400 {
+		return "", false, responseError("select", resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(dest); err != nil {
+		return "", false, fmt.Errorf("failed to decode select response: %w", err)
+	}
+	return resp.Header.Get("ETag"), true, nil
+}
+
+// selectRequest builds the GET request for Select and its variants.
+func (t *Table) selectRequest(jwtToken string) (*http.Request, error) {
+	if err := t.queryErr(); err != nil {
+		return nil, err
+	}
+	params := t.selectParams()
 
 	endpoint := fmt.Sprintf("%s%s/%s", t.client.BaseURL, REST_URL, t.tableName)
 	if len(params) > 0 {
@@ -242,36 +910,214 @@ func (t *Table) Select(dest interface{}, jwtToken string) error {
 
 	req, err := http.NewRequest("GET", endpoint, nil)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("apikey", t.client.APIKey)
 	if jwtToken != "" {
 		req.Header.Set("Authorization", "Bearer "+jwtToken)
 	}
 	req.Header.Set("Accept", "application/json")
+	if t.single {
+		req.Header.Set("Accept", "application/vnd.pgrst.object+json")
+	}
+	return req, nil
+}
+
+// SelectOne decodes the single row matching the filters into dest, a pointer to a struct or
+// map. It uses PostgREST's single-object mode, so the server checks the row count and no extra
+// rows are transferred: it returns ErrNoRows if no row matches and ErrMultipleRows if several
+// do. The table itself is not modified.
+func (t *Table) SelectOne(dest interface{}, jwtToken string) error {
+	return t.selectSingle(dest, "select", jwtToken)
+}
+
+// SelectMaps fetches the matching rows as maps keyed by column name, for tables whose schema
+// is not known at compile time.
+func (t *Table) SelectMaps(jwtToken string) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	if err := t.Select(&rows, jwtToken); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// SelectOneMaps fetches the single matching row as a map. Returns ErrNoRows if no row matches
+// and ErrMultipleRows if more than one does.
+func (t *Table) SelectOneMaps(jwtToken string) (map[string]interface{}, error) {
+	var row map[string]interface{}
+	if err := t.selectSingle(&row, "select", jwtToken); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// selectLazyPageSize is the number of rows SelectLazy fetches per request.
+const selectLazyPageSize = 1000
+
+// SelectResult is one value received from SelectLazy: a row, or the error that ended the
+// query.
+type SelectResult struct {
+	Row map[string]interface{}
+	Err error
+}
 
-	resp, err := t.client.Do(req)
+// SelectLazy is SelectLazyCtx with a background context. The caller must read the channel
+// until it is closed, or the fetching goroutine is never released; use SelectLazyCtx to be
+// able to stop early.
+func (t *Table) SelectLazy(jwtToken string) <-chan SelectResult {
+	return t.SelectLazyCtx(context.Background(), jwtToken)
+}
+
+// SelectLazyCtx fetches the matching rows page by page in a background goroutine and sends
+// them on the returned channel, so large results are processed without holding them all in
+// memory. Pages are fetched until one comes back empty, so results are complete even when the
+// project's max-rows setting is below the page size. Limit and Offset, if set, bound the rows
+// returned overall. Set an Order so that pages do not overlap while rows are being changed. A
+// failed request is sent as a result with Err set and ends the query; the channel is closed
+// when all rows have been sent, after an error, or when ctx is cancelled. The table itself is
+// not modified.
+//
+//	ctx, cancel := context.WithCancel(ctx)
+//	defer cancel()
+//	for res := range client.Table("events").Order(supabasego.OrderOption{Field: "id"}).SelectLazyCtx(ctx, jwt) {
+//		if res.Err != nil {
+//			return res.Err
+//		}
+//		process(res.Row)
+//	}
+func (t *Table) SelectLazyCtx(ctx context.Context, jwtToken string) <-chan SelectResult {
+	results := make(chan SelectResult)
+	q := t.Clone()
+	q.single = false
+	go func() {
+		defer close(results)
+		remaining := q.limit // Zero means no limit
+		for {
+			q.limit = selectLazyPageSize
+			if remaining > 0 && remaining < q.limit {
+				q.limit = remaining
+			}
+			rows, err := q.selectPage(ctx, jwtToken)
+			if err != nil {
+				if ctx.Err() == nil {
+					select {
+					case results <- SelectResult{Err: err}:
+					case <-ctx.Done():
+					}
+				}
+				return
+			}
+			for _, row := range rows {
+				select {
+				case results <- SelectResult{Row: row}:
+				case <-ctx.Done():
+					return
+				}
+			}
+			// A short page does not mean the end: PostgREST's max-rows setting may cap pages
+			// below selectLazyPageSize. Only an empty page does.
+			if len(rows) == 0 {
+				return
+			}
+			if remaining > 0 {
+				if remaining -= len(rows); remaining == 0 {
+					return
+				}
+			}
+			q.offset += len(rows)
+		}
+	}()
+	return results
+}
+
+// selectPage fetches the rows selected by t's current limit and offset.
+func (t *Table) selectPage(ctx context.Context, jwtToken string) ([]map[string]interface{}, error) {
+	req, err := t.selectRequest(jwtToken)
+	if err != nil {
+		return nil, err
+	}
+	var rows []map[string]interface{}
+	if err := t.client.doJSONWith(t.httpClient(), req.WithContext(ctx), "select", &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// First decodes the first row matching the filters into dest, a pointer to a struct or map.
+// Rows are sorted by the table's order, or by the primary key (TableOptions.PrimaryKey, default
+// "id") when none is set. Returns ErrNoRows if no row matches. The table itself is not modified.
+func (t *Table) First(dest interface{}, jwtToken string) error {
+	return t.edge(dest, false, jwtToken)
+}
+
+// Last is like First but returns the last row, reversing the sort order.
+func (t *Table) Last(dest interface{}, jwtToken string) error {
+	return t.edge(dest, true, jwtToken)
+}
+
+func (t *Table) edge(dest interface{}, last bool, jwtToken string) error {
+	q := t.Clone()
+	if len(q.orders) == 0 {
+		key := q.primaryKey
+		if key == "" {
+			key = "id"
+		}
+		for _, field := range strings.Split(key, ",") {
+			q.orders = append(q.orders, order{field: strings.TrimSpace(field), direction: "asc"})
+		}
+	}
+	if last {
+		for i, o := range q.orders {
+			if o.foreignTable == "" {
+				q.orders[i] = o.reversed()
+			}
+		}
+	}
+	q.limit, q.offset, q.pageErr = 1, 0, nil
+	op := "first"
+	if last {
+		op = "last"
+	}
+	return q.selectSingle(dest, op, jwtToken)
+}
+
+// selectSingle runs the select query on a copy of t in Single mode, so it fails with ErrNoRows
+// or ErrTooManyRows unless exactly one row matches. SelectOne, SelectOneMaps, First and Last
+// all go through it; op names the operation in errors.
+func (t *Table) selectSingle(dest interface{}, op string, jwtToken string) error {
+	q := t.Clone().Single()
+	req, err := q.selectRequest(jwtToken)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("supabase: select failed: %s", string(body))
+	return q.client.doJSONWith(q.httpClient(), req, op, dest)
+}
+
+// marshalBody marshals a request body to JSON, returning ErrBodyTooLarge if it exceeds
+// Config.MaxBodySize.
+func (t *Table) marshalBody(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
 	}
-	return json.NewDecoder(resp.Body).Decode(dest)
+	if max := t.client.maxBodySize; max > 0 && int64(len(b)) > max {
+		return nil, fmt.Errorf("%w: %d bytes, limit is %d", ErrBodyTooLarge, len(b), max)
+	}
+	return b, nil
 }
 
 // Insert inserts one or more records into the table.
 func (t *Table) Insert(record interface{}, jwtToken string) error {
+	if t.beforeInsert != nil {
+		if err := t.beforeInsert(record); err != nil {
+			return err
+		}
+	}
 	endpoint := fmt.Sprintf("%s%s/%s", t.client.BaseURL, REST_URL, t.tableName)
 
-	fmt.Printf("Endpoint: %s\n", endpoint)
-
-	b, err := json.Marshal(record)
-	fmt.Printf("Record: %s\n", string(b))
+	b, err := t.marshalBody(record)
 	if err != nil {
-		return fmt.Errorf("failed to marshal record: %w", err)
+		return err
 	}
 
 	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(b))
@@ -285,9 +1131,9 @@ func (t *Table) Insert(record interface{}, jwtToken string) error {
 		req.Header.Set("Authorization", "Bearer "+jwtToken)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Prefer", "return=representation") //
+	req.Header.Set("Prefer", t.returnPreference())
 
-	resp, err := t.client.Do(req)
+	resp, err := t.client.doWith(t.httpClient(), req)
 
 	if err != nil {
 		return fmt.Errorf("insert request failed: %w", err)
@@ -295,8 +1141,10 @@ func (t *Table) Insert(record interface{}, jwtToken string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("supabase: insert failed: %s", string(body))
+		return responseError("insert", resp)
+	}
+	if t.returnMinimal {
+		return nil
 	}
 
 	// Decode the response back into the provided pointer
@@ -307,31 +1155,74 @@ func (t *Table) Insert(record interface{}, jwtToken string) error {
 	return nil
 }
 
+// InsertMaps inserts rows given as maps, for tables whose schema is not known at compile time.
+// The records are always sent as a JSON array. Unless ReturnMinimal is set, each map is updated
+// in place with the row as stored, including DB-generated fields such as id and created_at.
+func (t *Table) InsertMaps(records []map[string]interface{}, jwtToken string) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if t.beforeInsert != nil {
+		if err := t.beforeInsert(records); err != nil {
+			return err
+		}
+	}
+	b, err := t.marshalBody(records)
+	if err != nil {
+		return err
+	}
+	req, err := t.client.newRawRequest("POST", REST_URL+"/"+t.tableName, bytes.NewReader(b), "application/json", jwtToken)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Prefer", t.returnPreference())
+	if t.returnMinimal {
+		return t.client.doJSONWith(t.httpClient(), req, "insert", nil)
+	}
+	var inserted []map[string]interface{}
+	if err := t.client.doJSONWith(t.httpClient(), req, "insert", &inserted); err != nil {
+		return err
+	}
+	for i, row := range inserted {
+		if i >= len(records) {
+			break
+		}
+		if records[i] == nil {
+			continue
+		}
+		for k, v := range row {
+			records[i][k] = v
+		}
+	}
+	return nil
+}
+
 // Update updates records matching filters with given values and decodes the updated rows into dest.
+// When dest is nil the response body is not decoded. A filter comparing against nil with an
+// operator other than Eq or NotEq is an error rather than being left out.
 func (t *Table) Update(values map[string]interface{}, dest interface{}, jwtToken string) error {
-	params := url.Values{}
-	for _, f := range t.filters {
-		switch filter := f.(type) {
-		case simpleFilter:
-			params.Add(filter.field, fmt.Sprintf("%s.%v", filter.op, filter.value))
-		case groupFilter:
-			params.Add(filter.operator, filter.toQuery()[len(filter.operator)+1:])
+	if t.beforeUpdate != nil {
+		if err := t.beforeUpdate(values); err != nil {
+			return err
 		}
 	}
-
+	params, err := t.mutationFilterParams()
+	if err != nil {
+		return err
+	}
 	endpoint := fmt.Sprintf("%s%s/%s", t.client.BaseURL, REST_URL, t.tableName)
 	if len(params) > 0 {
 		endpoint += "?" + params.Encode()
 	}
 
-	b, err := json.Marshal(values)
+	b, err := t.marshalBody(values)
 	if err != nil {
 		return err
 	}
 
 	req, err := http.NewRequest("PATCH", endpoint, bytes.NewReader(b))
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("apikey", t.client.APIKey)
@@ -341,25 +1232,69 @@ func (t *Table) Update(values map[string]interface{}, dest interface{}, jwtToken
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Prefer", "return=representation") //
 
-	resp, err := t.client.Do(req)
+	resp, err := t.client.doWith(t.httpClient(), req)
 	if err != nil {
-		return err
+		return fmt.Errorf("update request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("supabase: update failed: %s", string(body))
+		return responseError("update", resp)
 	}
 
+	if dest == nil {
+		return nil
+	}
 	return json.NewDecoder(resp.Body).Decode(dest)
 }
 
-// Delete deletes records matching filters from the table.
+// Upsert inserts record(s), merging into existing rows that conflict on the onConflict column(s)
+// (comma-separated; empty uses TableOptions.PrimaryKey if set, else the table's primary key).
+func (t *Table) Upsert(record interface{}, onConflict string, jwtToken string) error {
+	return t.upsert(record, nil, onConflict, jwtToken)
+}
+
+// UpsertResult is like Upsert but decodes the upserted rows into dest. When dest is nil the
+// response body is not decoded.
+func (t *Table) UpsertResult(record interface{}, dest interface{}, onConflict string, jwtToken string) error {
+	return t.upsert(record, dest, onConflict, jwtToken)
+}
+
+func (t *Table) upsert(record interface{}, dest interface{}, onConflict string, jwtToken string) error {
+	if t.beforeInsert != nil {
+		if err := t.beforeInsert(record); err != nil {
+			return err
+		}
+	}
+	if onConflict == "" {
+		onConflict = t.primaryKey
+	}
+	path := REST_URL + "/" + t.tableName
+	if onConflict != "" {
+		path += "?" + url.Values{"on_conflict": {onConflict}}.Encode()
+	}
+	b, err := t.marshalBody(record)
+	if err != nil {
+		return err
+	}
+	req, err := t.client.newRawRequest("POST", path, bytes.NewReader(b), "application/json", jwtToken)
+	if err != nil {
+		return err
+	}
+	if dest != nil {
+		req.Header.Set("Prefer", "resolution=merge-duplicates,return=representation")
+	} else {
+		req.Header.Set("Prefer", "resolution=merge-duplicates,return=minimal")
+	}
+	return t.client.doJSONWith(t.httpClient(), req, "upsert", dest)
+}
+
+// Delete deletes records matching filters from the table. As with Update, a filter comparing
+// against nil with an operator other than Eq or NotEq is an error rather than being left out.
 func (t *Table) Delete(jwtToken string) error {
-	params := url.Values{}
-	for _, f := range t.filters {
-		params.Add("or", f.toQuery())
+	params, err := t.mutationFilterParams()
+	if err != nil {
+		return err
 	}
 	endpoint := fmt.Sprintf("%s%s/%s", t.client.BaseURL, REST_URL, t.tableName)
 	if len(params) > 0 {
@@ -368,7 +1303,7 @@ func (t *Table) Delete(jwtToken string) error {
 
 	req, err := http.NewRequest("DELETE", endpoint, nil)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("apikey", t.client.APIKey)
 	if jwtToken != "" {
@@ -376,14 +1311,121 @@ func (t *Table) Delete(jwtToken string) error {
 	}
 	req.Header.Set("Prefer", "return=representation") // Return deleted rows
 
-	resp, err := t.client.Do(req)
+	resp, err := t.client.doWith(t.httpClient(), req)
 	if err != nil {
+		return fmt.Errorf("delete request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return responseError("delete", resp)
+	}
+	return nil
+}
+
+// DryRunSQL runs the select query the table would send with EXPLAIN ANALYZE inside a
+// transaction that is rolled back (Prefer: tx=rollback), and stores the plan as text in dest
+// instead of returning rows. This validates the query end to end, including RLS policies,
+// without side effects. The project must allow plans (db-plan-enabled) and transaction
+// overrides (db-tx-end = commit-allow-override) in its PostgREST settings.
+func (t *Table) DryRunSQL(dest *string, jwtToken string) error {
+	return t.explain(dest, "dry run", "application/vnd.pgrst.plan+text; options=analyze", "tx=rollback", jwtToken)
+}
+
+// SelectWithPlan is like Select but also stores the query's execution plan (EXPLAIN output, as
+// text) in planDest, to see how a query performs alongside its results. PostgREST cannot return
+// rows and a plan in one response, so this makes two requests: the plan is fetched first, then
+// the rows; they are not taken in the same transaction. The project must allow plans
+// (db-plan-enabled) in its PostgREST settings.
+func (t *Table) SelectWithPlan(dest interface{}, planDest *string, jwtToken string) error {
+	if err := t.explain(planDest, "explain", "application/vnd.pgrst.plan+text", "", jwtToken); err != nil {
 		return err
 	}
+	return t.Select(dest, jwtToken)
+}
+
+// explain sends the select query asking for its plan in the accept media type and stores the
+// plan in dest. prefer, if set, is sent as the Prefer header; op names the operation in errors.
+func (t *Table) explain(dest *string, op, accept, prefer string, jwtToken string) error {
+	if err := t.queryErr(); err != nil {
+		return err
+	}
+	req, err := t.client.newRequest("GET", REST_URL+"/"+t.tableName+"?"+t.selectParams().Encode(), nil, jwtToken)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", accept)
+	if prefer != "" {
+		req.Header.Set("Prefer", prefer)
+	}
+	resp, err := t.client.doWith(t.httpClient(), req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", op, err)
+	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("supabase: delete failed: %s", string(body))
+		return responseError(op, resp)
+	}
+	plan, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %w", op, err)
 	}
+	*dest = string(plan)
 	return nil
 }
+
+// Count returns the exact number of rows matching the filters (Prefer: count=exact).
+func (t *Table) Count(jwtToken string) (int64, error) {
+	return t.count("exact", jwtToken)
+}
+
+// SelectCount returns the number of rows the current filter chain matches without fetching
+// any row data (HEAD with Prefer: count=exact). It is equivalent to Count.
+func (t *Table) SelectCount(jwtToken string) (int64, error) {
+	return t.count("exact", jwtToken)
+}
+
+// ApproximateCount returns an approximate number of rows matching the filters, which is much
+// cheaper than Count on large tables. mode is "planned" (query planner estimate) or "estimated"
+// (exact count for small results, planner estimate beyond PostgREST's max-rows).
+func (t *Table) ApproximateCount(mode string, jwtToken string) (int64, error) {
+	if mode != "planned" && mode != "estimated" {
+		// -1 signals "no count" to callers that ignore the error.
+		return -1, fmt.Errorf("supabase: unsupported count mode %q", mode)
+	}
+	return t.count(mode, jwtToken)
+}
+
+func (t *Table) count(mode string, jwtToken string) (int64, error) {
+	if err := t.filtersErr(); err != nil {
+		return 0, err
+	}
+	params := t.filterParams()
+	params.Set("select", "*")
+	req, err := t.client.newRequest("HEAD", REST_URL+"/"+t.tableName+"?"+params.Encode(), nil, jwtToken)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Prefer", "count="+mode)
+	resp, err := t.client.doWith(t.httpClient(), req)
+	if err != nil {
+		return 0, fmt.Errorf("count request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return 0, responseError("count", resp)
+	}
+	return parseContentRange(resp.Header.Get("Content-Range"))
+}
+
+// parseContentRange extracts the total from a Content-Range header such as "0-24/3573" or "*/0".
+func parseContentRange(h string) (int64, error) {
+	i := strings.LastIndex(h, "/")
+	if i < 0 || h[i+1:] == "*" {
+		return 0, fmt.Errorf("supabase: no count in Content-Range %q", h)
+	}
+	n, err := strconv.ParseInt(h[i+1:], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("supabase: invalid Content-Range %q: %w", h, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,362 @@
+package supabasego
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// NewTestClient starts an httptest.Server serving handler and returns a Client pointed at it,
+// along with a function that shuts the server down. It lets tests run against a simulated
+// Supabase instead of a real project:
+//
+//	client, cleanup := supabasego.NewTestClient(supabasego.NewFakeSupabaseHandler())
+//	defer cleanup()
+func NewTestClient(handler http.Handler) (*Client, func()) {
+	srv := httptest.NewServer(handler)
+	client := NewClient(Config{BaseURL: srv.URL, APIKey: "test-api-key"})
+	return client, srv.Close
+}
+
+// FakeSupabaseHandler is an in-memory imitation of the PostgREST API for tests. It supports
+// select, insert, update and delete on any table, the eq, neq, gt, gte, lt, lte, is and in
+// filters, limit, offset, plain column selection, single-object responses, and the Prefer
+// return= and count= options. Anything else is rejected with a 400 error.
+type FakeSupabaseHandler struct {
+	mu     sync.Mutex
+	tables map[string][]map[string]interface{}
+}
+
+// NewFakeSupabaseHandler returns a FakeSupabaseHandler with no data. Tables are created on
+// first use.
+func NewFakeSupabaseHandler() *FakeSupabaseHandler {
+	return &FakeSupabaseHandler{tables: map[string][]map[string]interface{}{}}
+}
+
+// Seed appends rows to a table.
+func (h *FakeSupabaseHandler) Seed(table string, rows ...map[string]interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, row := range rows {
+		h.tables[table] = append(h.tables[table], copyRow(row))
+	}
+}
+
+// Rows returns a copy of the rows currently in a table.
+func (h *FakeSupabaseHandler) Rows(table string) []map[string]interface{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	rows := make([]map[string]interface{}, len(h.tables[table]))
+	for i, row := range h.tables[table] {
+		rows[i] = copyRow(row)
+	}
+	return rows
+}
+
+// fakeReservedParams are query parameters that are not column filters.
+var fakeReservedParams = map[string]bool{
+	"select": true, "order": true, "limit": true, "offset": true, "on_conflict": true, "columns": true,
+}
+
+// ServeHTTP implements http.Handler.
+func (h *FakeSupabaseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	table := strings.TrimPrefix(r.URL.Path, REST_URL+"/")
+	if table == r.URL.Path || table == "" || strings.Contains(table, "/") {
+		writeFakeError(w, http.StatusNotFound, "PGRST125", "invalid path "+r.URL.Path)
+		return
+	}
+	query := r.URL.Query()
+	var filters []fakeFilter
+	for key, values := range query {
+		if fakeReservedParams[key] {
+			continue
+		}
+		for _, v := range values {
+			f, err := parseFakeFilter(key, v)
+			if err != nil {
+				writeFakeError(w, http.StatusBadRequest, "PGRST100", err.Error())
+				return
+			}
+			filters = append(filters, f)
+		}
+	}
+	prefer := r.Header.Get("Prefer")
+	representation := strings.Contains(prefer, "return=representation")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		var matched []map[string]interface{}
+		for _, row := range h.tables[table] {
+			if matchesAll(row, filters) {
+				matched = append(matched, row)
+			}
+		}
+		total := len(matched)
+		offset, _ := strconv.Atoi(query.Get("offset"))
+		if offset > len(matched) {
+			offset = len(matched)
+		}
+		matched = matched[offset:]
+		if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit < len(matched) {
+			matched = matched[:limit]
+		}
+		out := make([]map[string]interface{}, len(matched))
+		for i, row := range matched {
+			out[i] = projectRow(row, query.Get("select"))
+		}
+		contentRange := "*/*"
+		if len(out) > 0 {
+			contentRange = fmt.Sprintf("%d-%d/*", offset, offset+len(out)-1)
+		}
+		if strings.Contains(prefer, "count=") {
+			contentRange = strings.TrimSuffix(contentRange, "*") + strconv.Itoa(total)
+		}
+		w.Header().Set("Content-Range", contentRange)
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if strings.Contains(r.Header.Get("Accept"), "application/vnd.pgrst.object+json") {
+			if len(out) != 1 {
+				writeFakeJSON(w, http.StatusNotAcceptable, map[string]interface{}{
+					"code": "PGRST116", "message": "JSON object requested, multiple (or no) rows returned",
+					"details": fmt.Sprintf("The result contains %d rows", len(out)), "hint": nil,
+				})
+				return
+			}
+			writeFakeJSON(w, http.StatusOK, out[0])
+			return
+		}
+		writeFakeJSON(w, http.StatusOK, out)
+	case http.MethodPost:
+		var rows []map[string]interface{}
+		if err := decodeFakeRows(r, &rows); err != nil {
+			writeFakeError(w, http.StatusBadRequest, "PGRST102", err.Error())
+			return
+		}
+		for _, row := range rows {
+			h.tables[table] = append(h.tables[table], copyRow(row))
+		}
+		h.respondRows(w, http.StatusCreated, representation, rows)
+	case http.MethodPatch:
+		var values map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&values); err != nil {
+			writeFakeError(w, http.StatusBadRequest, "PGRST102", err.Error())
+			return
+		}
+		var updated []map[string]interface{}
+		for _, row := range h.tables[table] {
+			if matchesAll(row, filters) {
+				for k, v := range values {
+					row[k] = v
+				}
+				updated = append(updated, copyRow(row))
+			}
+		}
+		h.respondRows(w, http.StatusOK, representation, updated)
+	case http.MethodDelete:
+		var kept, deleted []map[string]interface{}
+		for _, row := range h.tables[table] {
+			if matchesAll(row, filters) {
+				deleted = append(deleted, row)
+			} else {
+				kept = append(kept, row)
+			}
+		}
+		h.tables[table] = kept
+		h.respondRows(w, http.StatusOK, representation, deleted)
+	default:
+		writeFakeError(w, http.StatusMethodNotAllowed, "PGRST117", "unsupported method "+r.Method)
+	}
+}
+
+// respondRows writes rows as the response when the client asked for a representation, and an
+// empty 204 (or 201 for inserts) otherwise.
+func (h *FakeSupabaseHandler) respondRows(w http.ResponseWriter, status int, representation bool, rows []map[string]interface{}) {
+	if !representation {
+		if status != http.StatusCreated {
+			status = http.StatusNoContent
+		}
+		w.WriteHeader(status)
+		return
+	}
+	if rows == nil {
+		rows = []map[string]interface{}{}
+	}
+	writeFakeJSON(w, status, rows)
+}
+
+// decodeFakeRows decodes a request body holding either one object or an array of objects.
+func decodeFakeRows(r *http.Request, rows *[]map[string]interface{}) error {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		return err
+	}
+	if trimmed := strings.TrimSpace(string(raw)); strings.HasPrefix(trimmed, "[") {
+		return json.Unmarshal(raw, rows)
+	}
+	var row map[string]interface{}
+	if err := json.Unmarshal(raw, &row); err != nil {
+		return err
+	}
+	*rows = []map[string]interface{}{row}
+	return nil
+}
+
+// fakeFilter is a parsed column filter such as age=gte.18.
+type fakeFilter struct {
+	column string
+	op     string
+	args   []string
+}
+
+func parseFakeFilter(column, value string) (fakeFilter, error) {
+	op, arg, ok := strings.Cut(value, ".")
+	if !ok {
+		return fakeFilter{}, fmt.Errorf("failed to parse filter %s=%s", column, value)
+	}
+	switch op {
+	case "eq", "neq", "gt", "gte", "lt", "lte", "is":
+		return fakeFilter{column: column, op: op, args: []string{arg}}, nil
+	case "in":
+		if !strings.HasPrefix(arg, "(") || !strings.HasSuffix(arg, ")") {
+			return fakeFilter{}, fmt.Errorf("failed to parse filter %s=%s", column, value)
+		}
+		return fakeFilter{column: column, op: op, args: splitInList(arg[1 : len(arg)-1])}, nil
+	}
+	return fakeFilter{}, fmt.Errorf("unsupported operator %q in fake handler", op)
+}
+
+// splitInList splits the contents of an in.(...) list, honouring double-quoted values and
+// backslash escapes inside them.
+func splitInList(s string) []string {
+	var (
+		values  []string
+		cur     strings.Builder
+		quoted  bool
+		escaped bool
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			values = append(values, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	return append(values, cur.String())
+}
+
+func matchesAll(row map[string]interface{}, filters []fakeFilter) bool {
+	for _, f := range filters {
+		if !f.matches(row[f.column]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f fakeFilter) matches(v interface{}) bool {
+	s := fakeValueString(v)
+	switch f.op {
+	case "is":
+		return s == f.args[0]
+	case "in":
+		for _, arg := range f.args {
+			if s == arg {
+				return true
+			}
+		}
+		return false
+	case "eq":
+		return v != nil && s == f.args[0]
+	case "neq":
+		return v != nil && s != f.args[0]
+	}
+	if v == nil {
+		return false
+	}
+	cmp := strings.Compare(s, f.args[0])
+	if a, err := strconv.ParseFloat(s, 64); err == nil {
+		if b, err := strconv.ParseFloat(f.args[0], 64); err == nil {
+			cmp = 0
+			if a < b {
+				cmp = -1
+			} else if a > b {
+				cmp = 1
+			}
+		}
+	}
+	switch f.op {
+	case "gt":
+		return cmp > 0
+	case "gte":
+		return cmp >= 0
+	case "lt":
+		return cmp < 0
+	}
+	return cmp <= 0 // lte
+}
+
+// fakeValueString renders a decoded JSON value the way it appears in a filter.
+func fakeValueString(v interface{}) string {
+	switch vv := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return vv
+	case float64:
+		return strconv.FormatFloat(vv, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(vv)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// projectRow returns the columns of row named in a select list; "" or "*" selects all.
+func projectRow(row map[string]interface{}, sel string) map[string]interface{} {
+	if sel == "" || sel == "*" {
+		return copyRow(row)
+	}
+	out := map[string]interface{}{}
+	for _, col := range strings.Split(sel, ",") {
+		col = strings.TrimSpace(col)
+		if v, ok := row[col]; ok {
+			out[col] = v
+		}
+	}
+	return out
+}
+
+func copyRow(row map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		out[k] = v
+	}
+	return out
+}
+
+func writeFakeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeFakeError(w http.ResponseWriter, status int, code, message string) {
+	writeFakeJSON(w, status, map[string]interface{}{"code": code, "message": message, "details": nil, "hint": nil})
+}
@@ -6,7 +6,34 @@ const (
 	STORAGE_URL   = "/storage/v1"
 	AUTH_URL      = "/auth/v1"
 	FUNCTIONS_URL = "/functions/v1"
+	REALTIME_URL  = "/realtime/v1"
+
+	// LOCAL_FUNCTIONS_URL is where the Supabase CLI serves Edge Functions during local development.
+	LOCAL_FUNCTIONS_URL = "http://localhost:54321/functions/v1"
+
+	// MANAGEMENT_API_URL is the Supabase Management API, used for project-level administration.
+	MANAGEMENT_API_URL = "https://api.supabase.com/v1"
 )
 
+// isUUID reports whether s is a UUID in canonical 8-4-4-4-12 hex form.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			if !('0' <= r && r <= '9' || 'a' <= r && r <= 'f' || 'A' <= r && r <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 // Shared types for CRUD, query options, etc. will go here.
 // For example, you may define error types, response wrappers, etc.